@@ -0,0 +1,465 @@
+/*
+	通用缓存接口及实现：进程内缓存（LRU淘汰+过期时间）、Redis缓存、两级缓存（进程内+Redis）
+	两级缓存修改或删除数据时通过Redis发布订阅通知其他节点删除进程内缓存，进程内缓存的时间不超过LocalTTL
+	查询结果缓存（Db.QueryCache）同样使用此接口作为存储
+	使用方法：
+	c := Cache.NewLayeredCache(r, Cache.NewMemoryCache(10000))
+	defer c.Close()
+	err := c.Set("user:1", data, time.Minute)
+	data, ok := c.Get("user:1")
+	fmt.Println(c.Stats().HitRate())
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
+)
+
+const (
+	CacheModeMemory  = "memory"  //进程内缓存
+	CacheModeRedis   = "redis"   //Redis缓存
+	CacheModeLayered = "layered" //两级缓存
+
+	InvalidateChannel = "cache:invalidate" //两级缓存的失效通知频道
+)
+
+var (
+	DefaultMaxEntries = 10000       //进程内缓存默认的最大条数
+	DefaultLocalTTL   = time.Minute //两级缓存中进程内缓存默认的最长时间
+)
+
+type (
+	//缓存接口
+	Cache interface {
+		Get(key string) ([]byte, bool)
+		Set(key string, val []byte, ttl time.Duration) error //ttl为0时不过期
+		Delete(keys ...string) error
+		TTL(key string) (time.Duration, bool) //剩余时间，不过期时为0，不存在时返回false
+	}
+
+	//缓存统计
+	CacheStats struct {
+		Hits      uint64 //命中次数
+		LocalHits uint64 //两级缓存中进程内缓存的命中次数（包含在Hits中）
+		Misses    uint64 //未命中次数
+		Sets      uint64 //保存次数
+		Deletes   uint64 //删除次数
+		Evictions uint64 //进程内缓存超出最大条数被淘汰的次数
+	}
+
+	//缓存计数器
+	cacheCounter struct {
+		hits, localHits, misses, sets, deletes, evictions uint64
+	}
+
+	//进程内缓存，超出最大条数时淘汰最久未使用的数据
+	MemoryCache struct {
+		MaxEntries int //最大条数，小于等于0时不限制
+
+		mu      sync.Mutex
+		ll      *list.List
+		items   map[string]*list.Element
+		counter cacheCounter
+	}
+	memoryEntry struct {
+		key    string
+		val    []byte
+		expire time.Time
+	}
+
+	//Redis缓存，Key自动添加Key前缀
+	RedisCache struct {
+		r       *RedisModel
+		counter cacheCounter
+	}
+
+	//两级缓存，先读进程内缓存，未命中时读Redis并写入进程内缓存
+	LayeredCache struct {
+		Local    *MemoryCache
+		Remote   *RedisCache
+		LocalTTL time.Duration //进程内缓存的最长时间，用于限制失效通知丢失（如网络中断）时读到旧数据的时间
+
+		r       *RedisModel
+		node    string //当前节点标识，忽略自己发出的通知
+		cancel  context.CancelFunc
+		counter cacheCounter
+	}
+
+	//失效通知
+	invalidateMessage struct {
+		Node string   `json:"n"`
+		Keys []string `json:"k"`
+	}
+)
+
+//根据frame.CacheMode创建缓存，默认为进程内缓存
+//@param r Redis对象，redis及layered模式使用
+func NewDefaultCache(r *RedisModel) (Cache, error) {
+	switch strings.ToLower(frame.CacheMode) {
+	case "", CacheModeMemory:
+		return NewMemoryCache(DefaultMaxEntries), nil
+	case CacheModeRedis:
+		if r == nil {
+			return nil, fmt.Errorf("缓存模式[%s]需要Redis对象", frame.CacheMode)
+		}
+		return NewRedisCache(r), nil
+	case CacheModeLayered:
+		if r == nil {
+			return nil, fmt.Errorf("缓存模式[%s]需要Redis对象", frame.CacheMode)
+		}
+		return NewLayeredCache(r, NewMemoryCache(DefaultMaxEntries)), nil
+	}
+	return nil, fmt.Errorf("不支持的缓存模式[%s]", frame.CacheMode)
+}
+
+//命中率
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (c *cacheCounter) stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		LocalHits: atomic.LoadUint64(&c.localHits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Sets:      atomic.LoadUint64(&c.sets),
+		Deletes:   atomic.LoadUint64(&c.deletes),
+		Evictions: atomic.LoadUint64(&c.evictions),
+	}
+}
+
+//-----进程内缓存-----
+
+//创建进程内缓存
+//@param maxEntries 最大条数，小于等于0时不限制
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		MaxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		if entry.expire.IsZero() || time.Now().Before(entry.expire) {
+			c.ll.MoveToFront(el)
+			atomic.AddUint64(&c.counter.hits, 1)
+			return entry.val, true
+		}
+		c.remove(el)
+	}
+	atomic.AddUint64(&c.counter.misses, 1)
+	return nil, false
+}
+
+func (c *MemoryCache) Set(key string, val []byte, ttl time.Duration) error {
+	var expire time.Time
+	if ttl > 0 {
+		expire = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	atomic.AddUint64(&c.counter.sets, 1)
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.val, entry.expire = val, expire
+		c.ll.MoveToFront(el)
+		return nil
+	}
+	c.items[key] = c.ll.PushFront(&memoryEntry{key: key, val: val, expire: expire})
+	for c.MaxEntries > 0 && c.ll.Len() > c.MaxEntries {
+		c.remove(c.ll.Back())
+		atomic.AddUint64(&c.counter.evictions, 1)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+			atomic.AddUint64(&c.counter.deletes, 1)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) TTL(key string) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return 0, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if entry.expire.IsZero() {
+		return 0, true
+	}
+	ttl := time.Until(entry.expire)
+	if ttl <= 0 {
+		c.remove(el)
+		return 0, false
+	}
+	return ttl, true
+}
+
+//缓存条数（包含已过期未清理的数据）
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+//清空缓存
+func (c *MemoryCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+//缓存统计
+func (c *MemoryCache) Stats() CacheStats {
+	return c.counter.stats()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*memoryEntry).key)
+}
+
+//-----Redis缓存-----
+
+//创建Redis缓存
+func NewRedisCache(r *RedisModel) *RedisCache {
+	return &RedisCache{r: r}
+}
+
+func (c *RedisCache) Get(key string) ([]byte, bool) {
+	val, err := redis.Bytes(c.r.Query("GET", c.r.GenerateKey(key)))
+	if err != nil {
+		if err != redis.ErrNil {
+			Text.Log("error").Error(fmt.Sprintf("redis cache get error:%s", err.Error()))
+		}
+		atomic.AddUint64(&c.counter.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&c.counter.hits, 1)
+	return val, true
+}
+
+func (c *RedisCache) Set(key string, val []byte, ttl time.Duration) error {
+	atomic.AddUint64(&c.counter.sets, 1)
+	var err error
+	if ttl > 0 {
+		ms := int64(ttl / time.Millisecond)
+		if ms < 1 {
+			ms = 1
+		}
+		_, err = c.r.Do("SET", c.r.GenerateKey(key), val, "PX", ms)
+	} else {
+		_, err = c.r.Do("SET", c.r.GenerateKey(key), val)
+	}
+	return err
+}
+
+func (c *RedisCache) Delete(keys ...string) error {
+	if len(keys) < 1 {
+		return nil
+	}
+	atomic.AddUint64(&c.counter.deletes, uint64(len(keys)))
+	_, err := c.r.Do("DEL", c.r.generateKeys(keys)...)
+	return err
+}
+
+func (c *RedisCache) TTL(key string) (time.Duration, bool) {
+	ms, err := redis.Int64(c.r.Query("PTTL", c.r.GenerateKey(key)))
+	return pttlDuration(ms, err)
+}
+
+//缓存统计
+func (c *RedisCache) Stats() CacheStats {
+	return c.counter.stats()
+}
+
+//一次请求获取值及剩余时间
+func (c *RedisCache) getWithTTL(key string) ([]byte, time.Duration, bool) {
+	conn, err := c.r.getConn(c.r.redisReader, c.r.readerSettings)
+	if err == nil {
+		defer conn.Close()
+		key = c.r.GenerateKey(key)
+		conn.Send("GET", key)
+		conn.Send("PTTL", key)
+		if err = conn.Flush(); err == nil {
+			var val []byte
+			val, err = redis.Bytes(conn.Receive())
+			ttl, ok := pttlDuration(redis.Int64(conn.Receive()))
+			if err == nil && ok {
+				atomic.AddUint64(&c.counter.hits, 1)
+				return val, ttl, true
+			}
+		}
+	}
+	if err != nil && err != redis.ErrNil {
+		Text.Log("error").Error(fmt.Sprintf("redis cache get error:%s", err.Error()))
+	}
+	atomic.AddUint64(&c.counter.misses, 1)
+	return nil, 0, false
+}
+
+//将PTTL的结果转换为剩余时间，-1（不过期）为0，-2（不存在）返回false
+func pttlDuration(ms int64, err error) (time.Duration, bool) {
+	if err != nil || ms == -2 {
+		return 0, false
+	}
+	if ms < 0 {
+		return 0, true
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+//-----两级缓存-----
+
+//创建两级缓存并订阅失效通知，不再使用时调用Close取消订阅
+//@param local 进程内缓存，为nil时创建最大条数为DefaultMaxEntries的缓存
+func NewLayeredCache(r *RedisModel, local *MemoryCache) *LayeredCache {
+	if local == nil {
+		local = NewMemoryCache(DefaultMaxEntries)
+	}
+	node, _ := lockToken()
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &LayeredCache{
+		Local:    local,
+		Remote:   NewRedisCache(r),
+		LocalTTL: DefaultLocalTTL,
+		r:        r,
+		node:     node,
+		cancel:   cancel,
+	}
+	go c.subscribe(ctx)
+	return c
+}
+
+func (c *LayeredCache) Get(key string) ([]byte, bool) {
+	if val, ok := c.Local.Get(key); ok {
+		atomic.AddUint64(&c.counter.hits, 1)
+		atomic.AddUint64(&c.counter.localHits, 1)
+		return val, true
+	}
+	val, ttl, ok := c.Remote.getWithTTL(key)
+	if !ok {
+		atomic.AddUint64(&c.counter.misses, 1)
+		return nil, false
+	}
+	c.Local.Set(key, val, c.localTTL(ttl))
+	atomic.AddUint64(&c.counter.hits, 1)
+	return val, true
+}
+
+func (c *LayeredCache) Set(key string, val []byte, ttl time.Duration) error {
+	atomic.AddUint64(&c.counter.sets, 1)
+	if err := c.Remote.Set(key, val, ttl); err != nil {
+		c.Local.Delete(key)
+		return err
+	}
+	c.Local.Set(key, val, c.localTTL(ttl))
+	c.publish(key)
+	return nil
+}
+
+func (c *LayeredCache) Delete(keys ...string) error {
+	if len(keys) < 1 {
+		return nil
+	}
+	atomic.AddUint64(&c.counter.deletes, uint64(len(keys)))
+	c.Local.Delete(keys...)
+	err := c.Remote.Delete(keys...)
+	c.publish(keys...)
+	return err
+}
+
+func (c *LayeredCache) TTL(key string) (time.Duration, bool) {
+	return c.Remote.TTL(key)
+}
+
+//清空所有节点的进程内缓存，Redis中的数据不受影响
+func (c *LayeredCache) ClearLocal() {
+	c.Local.Clear()
+	c.publish()
+}
+
+//缓存统计
+func (c *LayeredCache) Stats() CacheStats {
+	return c.counter.stats()
+}
+
+//取消失效通知的订阅
+func (c *LayeredCache) Close() error {
+	c.cancel()
+	return nil
+}
+
+//进程内缓存的时间，不超过LocalTTL
+func (c *LayeredCache) localTTL(ttl time.Duration) time.Duration {
+	if c.LocalTTL > 0 && (ttl <= 0 || ttl > c.LocalTTL) {
+		return c.LocalTTL
+	}
+	return ttl
+}
+
+//通知其他节点删除进程内缓存，keys为空时清空
+func (c *LayeredCache) publish(keys ...string) {
+	data, _ := json.Marshal(&invalidateMessage{Node: c.node, Keys: keys})
+	if _, err := c.r.Publish(InvalidateChannel, data); err != nil {
+		Text.Log("error").Error(fmt.Sprintf("redis cache invalidate error:%s", err.Error()))
+	}
+}
+
+//订阅失效通知，首次链接失败时重试
+func (c *LayeredCache) subscribe(ctx context.Context) {
+	for {
+		err := c.r.Subscribe(ctx, func(msg *Message) {
+			var m invalidateMessage
+			if json.Unmarshal(msg.Data, &m) != nil || m.Node == c.node {
+				return
+			}
+			if len(m.Keys) < 1 {
+				c.Local.Clear()
+			} else {
+				c.Local.Delete(m.Keys...)
+			}
+		}, InvalidateChannel)
+		if ctx.Err() != nil {
+			return
+		}
+		Text.Log("error").Error(fmt.Sprintf("redis cache subscribe error:%v", err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(PubSubMaxBackoff):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package Cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/framework"
+)
+
+//进程内缓存的读写、过期及删除
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(0)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 20*time.Millisecond)
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Fatalf("读取结果错误：%s %v", v, ok)
+	}
+	if ttl, ok := c.TTL("a"); !ok || ttl != 0 {
+		t.Errorf("不过期的数据TTL应为0：%v %v", ttl, ok)
+	}
+	if ttl, ok := c.TTL("b"); !ok || ttl <= 0 || ttl > 20*time.Millisecond {
+		t.Errorf("TTL错误：%v %v", ttl, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("b"); ok {
+		t.Error("过期的数据不应读取到")
+	}
+	if _, ok := c.TTL("b"); ok {
+		t.Error("过期的数据TTL应返回false")
+	}
+	c.Delete("a", "missing")
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("删除后不应读取到")
+	}
+	s := c.Stats()
+	if s.Hits != 1 || s.Misses != 2 || s.Sets != 2 || s.Deletes != 1 || s.HitRate() != float64(1)/3 {
+		t.Errorf("统计错误：%+v", s)
+	}
+}
+
+//超过最大条数时淘汰最近最少使用的数据
+func TestMemoryCacheEviction(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+	c.Get("a") //a最近使用
+	c.Set("c", []byte("3"), 0)
+	if _, ok := c.Get("b"); ok {
+		t.Error("应淘汰最近最少使用的b")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a不应被淘汰")
+	}
+	c.Set("a", []byte("4"), 0) //更新已存在的数据不淘汰
+	if v, _ := c.Get("a"); string(v) != "4" || c.Len() != 2 || c.Stats().Evictions != 1 {
+		t.Errorf("更新后结果错误：%s %d %+v", v, c.Len(), c.Stats())
+	}
+	c.Clear()
+	if c.Len() != 0 {
+		t.Error("清空后缓存条数应为0")
+	}
+}
+
+//PTTL结果的转换
+func TestPttlDuration(t *testing.T) {
+	cases := []struct {
+		ms  int64
+		err error
+		ttl time.Duration
+		ok  bool
+	}{
+		{1500, nil, 1500 * time.Millisecond, true},
+		{-1, nil, 0, true},
+		{-2, nil, 0, false},
+		{10, errors.New("conn error"), 0, false},
+	}
+	for _, c := range cases {
+		if ttl, ok := pttlDuration(c.ms, c.err); ttl != c.ttl || ok != c.ok {
+			t.Errorf("%d %v: 结果错误：%v %v", c.ms, c.err, ttl, ok)
+		}
+	}
+}
+
+//按缓存模式创建缓存，redis及layered模式需要Redis对象
+func TestNewDefaultCache(t *testing.T) {
+	old := frame.CacheMode
+	defer func() { frame.CacheMode = old }()
+	cases := []struct {
+		mode string
+		ok   bool
+	}{
+		{"", true},
+		{CacheModeMemory, true},
+		{CacheModeRedis, false},
+		{CacheModeLayered, false},
+		{"disk", false},
+	}
+	for _, c := range cases {
+		frame.CacheMode = c.mode
+		cache, err := NewDefaultCache(nil)
+		if (err == nil) != c.ok {
+			t.Errorf("%q: 结果错误：%v", c.mode, err)
+		}
+		if c.ok {
+			if _, ok := cache.(*MemoryCache); !ok {
+				t.Errorf("%q: 应为进程内缓存：%T", c.mode, cache)
+			}
+		}
+	}
+}
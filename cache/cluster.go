@@ -0,0 +1,737 @@
+/*
+	Redis集群模式，设置ClusterNodes后通过CLUSTER SLOTS获取槽位分布，按Key的槽位（CRC16）将命令发送到对应的主库，每个节点使用独立的缓存池
+	收到MOVED时更新槽位并重新获取集群信息，收到ASK时向目标节点发送ASKING后重试
+	MGET、DEL、UNLINK、EXISTS、TOUCH、MSET按槽位拆分执行后合并结果，管道中的命令按节点分组发送
+	事务（WATCH/MULTI）及Lua脚本的Key需要在同一槽位，可使用{hashtag}，如"user:{1001}:info"与"user:{1001}:orders"
+	Key前缀参与槽位计算，KeyPre中包含{hashtag}时所有Key在同一槽位；KEYS、INFO等无Key的命令只在其中一个节点执行
+	使用方法（配置文件）：
+	"default": {
+		"master": {"cluster_nodes": "10.0.0.1:7000;10.0.0.2:7000;10.0.0.3:7000", "password": "..."},
+		"slave":  {"cluster_nodes": "10.0.0.1:7000;10.0.0.2:7000;10.0.0.3:7000", "password": "..."}
+	}
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+	"github.com/misgo/aresgo/text"
+)
+
+const clusterSlots = 16384 //集群槽位数
+
+var (
+	ClusterMaxRedirects = 5 //MOVED/ASK的最大重定向次数
+
+	errClusterNoNode     = errors.New("集群没有可用的节点")
+	errClusterConnClosed = errors.New("集群链接已关闭")
+	errClusterNoReply    = errors.New("没有待接收的返回值")
+
+	//多Key命令及每个Key占用的参数个数，Key在不同槽位时拆分执行
+	clusterMultiKeyCommands = map[string]int{"MGET": 1, "DEL": 1, "UNLINK": 1, "EXISTS": 1, "TOUCH": 1, "MSET": 2}
+
+	//无Key的命令，发送到任意一个节点
+	clusterNoKeyCommands = map[string]bool{
+		"PING": true, "ECHO": true, "INFO": true, "TIME": true, "DBSIZE": true, "RANDOMKEY": true, "KEYS": true, "SCAN": true,
+		"SCRIPT": true, "CLUSTER": true, "PUBLISH": true, "CONFIG": true, "CLIENT": true, "COMMAND": true, "ROLE": true,
+		"SELECT": true, "AUTH": true, "FLUSHDB": true, "FLUSHALL": true, "LASTSAVE": true, "SLOWLOG": true,
+		"MULTI": true, "EXEC": true, "DISCARD": true, "UNWATCH": true, "WAIT": true, "ASKING": true, "READONLY": true, "READWRITE": true,
+	}
+
+	crc16Table [256]uint16
+)
+
+type (
+	//集群的槽位及节点缓存池
+	redisCluster struct {
+		r        *RedisModel
+		settings *RedisSettings
+
+		mu    sync.RWMutex
+		slots []string               //槽位对应的主库地址
+		pools map[string]*redis.Pool //节点地址对应的缓存池
+
+		refreshMu  sync.Mutex
+		refreshing int32 //是否正在后台刷新槽位
+	}
+
+	//集群链接，实现redis.Conn接口，按Key将命令发送到对应的节点
+	//WATCH或MULTI后固定使用第一个Key所在节点的链接，直到链接关闭
+	clusterConn struct {
+		c       *redisCluster
+		pending []clusterCmd   //Send后未发送的命令
+		replies []clusterReply //已执行未接收的返回值
+		pinned  redis.Conn     //事务使用的节点链接
+		multi   bool           //是否已开始事务
+		closed  bool
+	}
+	clusterCmd struct {
+		name string
+		args []interface{}
+	}
+	clusterReply struct {
+		val interface{}
+		err error
+	}
+)
+
+func init() {
+	for i := range crc16Table {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		crc16Table[i] = crc
+	}
+}
+
+//计算Key所在的槽位（自动添加Key前缀）
+func (r *RedisModel) KeySlot(key string) int {
+	return keySlot(r.GenerateKey(key))
+}
+
+//计算槽位，Key中包含非空的{hashtag}时只计算第一个{}中的内容
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^key[i]]
+	}
+	return int(crc) % clusterSlots
+}
+
+//创建集群，获取槽位失败时记录日志，执行命令时按MOVED更新槽位
+func newRedisCluster(r *RedisModel, settings *RedisSettings) *redisCluster {
+	c := &redisCluster{
+		r:        r,
+		settings: settings,
+		slots:    make([]string, clusterSlots),
+		pools:    make(map[string]*redis.Pool),
+	}
+	if err := c.refresh(); err != nil {
+		Text.Log("error").Error(fmt.Sprintf("redis cluster error:%s", err.Error()))
+	}
+	return c
+}
+
+//通过CLUSTER SLOTS获取槽位分布，按顺序尝试已知的节点
+func (c *redisCluster) refresh() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	lastErr := errClusterNoNode
+	for _, addr := range c.addrs() {
+		slots, err := c.fetchSlots(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("获取集群槽位失败：%v", lastErr)
+}
+
+//后台刷新槽位，正在刷新时忽略
+func (c *redisCluster) refreshAsync() {
+	if !atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&c.refreshing, 0)
+		if err := c.refresh(); err != nil {
+			Text.Log("error").Error(fmt.Sprintf("redis cluster error:%s", err.Error()))
+		}
+	}()
+}
+
+//从节点获取槽位分布
+func (c *redisCluster) fetchSlots(addr string) ([]string, error) {
+	conn := c.pool(addr).Get()
+	defer conn.Close()
+	reply, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+	slots := make([]string, clusterSlots)
+	for _, item := range reply {
+		info, err := redis.Values(item, nil)
+		if err != nil || len(info) < 3 {
+			continue
+		}
+		start, _ := redis.Int(info[0], nil)
+		end, _ := redis.Int(info[1], nil)
+		node, err := redis.Values(info[2], nil)
+		if err != nil || len(node) < 2 {
+			continue
+		}
+		ip, _ := redis.String(node[0], nil)
+		port, _ := redis.Int(node[1], nil)
+		if ip == "" { //节点未配置地址时使用当前链接的地址
+			ip, _, _ = net.SplitHostPort(addr)
+		}
+		master := net.JoinHostPort(ip, strconv.Itoa(port))
+		for i := start; i <= end && i < clusterSlots; i++ {
+			if i >= 0 {
+				slots[i] = master
+			}
+		}
+	}
+	return slots, nil
+}
+
+//已知的节点地址：槽位中的主库及配置的节点
+func (c *redisCluster) addrs() []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	c.mu.RLock()
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	c.mu.RUnlock()
+	for _, addr := range c.settings.ClusterNodes {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+//任意一个节点的地址
+func (c *redisCluster) anyAddr() string {
+	if addrs := c.addrs(); len(addrs) > 0 {
+		return addrs[0]
+	}
+	return ""
+}
+
+//命令发送到的节点地址，无Key或槽位未知时使用任意节点
+func (c *redisCluster) nodeFor(cmd string, args []interface{}) string {
+	key, ok := commandKey(cmd, args)
+	if !ok {
+		return c.anyAddr()
+	}
+	c.mu.RLock()
+	addr := c.slots[keySlot(key)]
+	c.mu.RUnlock()
+	if addr == "" {
+		return c.anyAddr()
+	}
+	return addr
+}
+
+//更新槽位对应的节点
+func (c *redisCluster) setSlot(slot int, addr string) {
+	if slot < 0 || slot >= clusterSlots {
+		return
+	}
+	c.mu.Lock()
+	c.slots[slot] = addr
+	c.mu.Unlock()
+}
+
+//获取节点的缓存池，不存在时创建
+func (c *redisCluster) pool(addr string) *redis.Pool {
+	c.mu.RLock()
+	pool, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return pool
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pool, ok = c.pools[addr]; ok {
+		return pool
+	}
+	settings := c.settings
+	pool = &redis.Pool{
+		MaxIdle:     settings.MaxIdle,
+		IdleTimeout: time.Duration(settings.IdleTimeout) * time.Second,
+		MaxActive:   settings.MaxActive,
+		Dial: func() (redis.Conn, error) {
+			return c.r.dialAddr(addr, settings)
+		},
+		TestOnBorrow: func(rc redis.Conn, t time.Time) error {
+			_, err := rc.Do("PING")
+			return err
+		},
+	}
+	c.pools[addr] = pool
+	return pool
+}
+
+//获取节点的链接
+func (c *redisCluster) nodeConn(addr string) (redis.Conn, error) {
+	if addr == "" {
+		return nil, errClusterNoNode
+	}
+	conn := c.pool(addr).Get()
+	if err := conn.Err(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+//所有主库的地址，未获取到槽位时使用配置的节点
+func (c *redisCluster) masters() []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	c.mu.RLock()
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	c.mu.RUnlock()
+	if len(addrs) < 1 {
+		return c.settings.ClusterNodes
+	}
+	return addrs
+}
+
+//依次使用每个主库的链接执行fn，fn返回false时停止
+func (c *redisCluster) eachMaster(fn func(conn redis.Conn) (bool, error)) error {
+	for _, addr := range c.masters() {
+		conn, err := c.nodeConn(addr)
+		if err != nil {
+			return err
+		}
+		next, err := fn(conn)
+		conn.Close()
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+//创建任意一个可用节点的链接（不使用缓存池）
+func (c *redisCluster) dial(options ...redis.DialOption) (redis.Conn, error) {
+	lastErr := errClusterNoNode
+	for _, addr := range c.addrs() {
+		rc, err := c.r.dialAddr(addr, c.settings, options...)
+		if err == nil {
+			return rc, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+//检查集群是否可用
+func (c *redisCluster) ping() error {
+	if err := c.refresh(); err != nil {
+		return err
+	}
+	_, err := c.do("PING")
+	return err
+}
+
+//关闭所有节点的缓存池
+func (c *redisCluster) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for addr, pool := range c.pools {
+		if perr := pool.Close(); err == nil {
+			err = perr
+		}
+		delete(c.pools, addr)
+	}
+	return err
+}
+
+//获取集群链接
+func (c *redisCluster) conn() redis.Conn {
+	return &clusterConn{c: c}
+}
+
+//执行命令，多Key命令的Key在不同槽位时拆分执行
+func (c *redisCluster) do(cmd string, args ...interface{}) (interface{}, error) {
+	if groups := splitKeys(cmd, args); len(groups) > 1 {
+		return c.doMulti(cmd, args, groups)
+	}
+	return c.doKey(cmd, args)
+}
+
+//在Key所在的节点执行命令，处理MOVED/ASK重定向；节点无法链接时重新获取槽位后重试一次
+func (c *redisCluster) doKey(cmd string, args []interface{}) (interface{}, error) {
+	addr := c.nodeFor(cmd, args)
+	asking, refreshed := false, false
+	var err error
+	for i := 0; i <= ClusterMaxRedirects; i++ {
+		var conn redis.Conn
+		if conn, err = c.nodeConn(addr); err != nil {
+			if refreshed || c.refresh() != nil {
+				return nil, err
+			}
+			refreshed = true
+			addr = c.nodeFor(cmd, args)
+			continue
+		}
+		if asking {
+			conn.Send("ASKING")
+		}
+		var reply interface{}
+		reply, err = conn.Do(cmd, args...)
+		conn.Close()
+		kind, target, slot := parseRedirect(err)
+		switch kind {
+		case "MOVED":
+			c.setSlot(slot, target)
+			c.refreshAsync()
+			addr, asking = target, false
+		case "ASK":
+			addr, asking = target, true
+		default:
+			return reply, err
+		}
+	}
+	return nil, err
+}
+
+//按槽位拆分执行多Key命令并合并结果：MGET按原顺序返回值，MSET返回OK，其他命令返回数量之和
+//@param groups 按槽位分组的Key在args中的位置
+func (c *redisCluster) doMulti(cmd string, args []interface{}, groups [][]int) (interface{}, error) {
+	upper := strings.ToUpper(cmd)
+	step := clusterMultiKeyCommands[upper]
+	values := make([]interface{}, len(args))
+	var total int64
+	for _, group := range groups {
+		var sub []interface{}
+		for _, k := range group {
+			sub = append(sub, args[k:k+step]...)
+		}
+		reply, err := c.doKey(cmd, sub)
+		if err != nil {
+			return nil, err
+		}
+		switch upper {
+		case "MGET":
+			vals, err := redis.Values(reply, nil)
+			if err != nil {
+				return nil, err
+			}
+			for j, k := range group {
+				if j < len(vals) {
+					values[k] = vals[j]
+				}
+			}
+		case "MSET":
+		default:
+			n, err := redis.Int64(reply, nil)
+			if err != nil {
+				return nil, err
+			}
+			total += n
+		}
+	}
+	switch upper {
+	case "MGET":
+		return values, nil
+	case "MSET":
+		return "OK", nil
+	}
+	return total, nil
+}
+
+//管道执行多条命令，按节点分组发送，重定向及跨槽位的多Key命令单独执行
+func (c *redisCluster) pipeline(cmds []clusterCmd) []clusterReply {
+	replies := make([]clusterReply, len(cmds))
+	groups := make(map[string][]int)
+	var redirected, single []int
+	var addrs []string
+	for i, cmd := range cmds {
+		if len(splitKeys(cmd.name, cmd.args)) > 1 {
+			single = append(single, i)
+			continue
+		}
+		addr := c.nodeFor(cmd.name, cmd.args)
+		if _, ok := groups[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+		groups[addr] = append(groups[addr], i)
+	}
+	for _, addr := range addrs {
+		idx := groups[addr]
+		conn, err := c.nodeConn(addr)
+		if err != nil { //命令未发送，单独执行时重新获取槽位
+			single = append(single, idx...)
+			continue
+		}
+		for _, i := range idx {
+			if err = conn.Send(cmds[i].name, cmds[i].args...); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = conn.Flush()
+		}
+		for _, i := range idx {
+			if err != nil {
+				replies[i].err = err
+				continue
+			}
+			val, rerr := conn.Receive()
+			if _, ok := rerr.(redis.Error); rerr != nil && !ok { //链接错误，后续返回值不可用
+				err = rerr
+			}
+			replies[i] = clusterReply{val: val, err: rerr}
+			if kind, _, _ := parseRedirect(rerr); kind != "" {
+				redirected = append(redirected, i)
+			}
+		}
+		conn.Close()
+	}
+	for _, i := range append(redirected, single...) {
+		replies[i].val, replies[i].err = c.do(cmds[i].name, cmds[i].args...)
+	}
+	return replies
+}
+
+//获取命令的Key，无Key时返回false
+func commandKey(cmd string, args []interface{}) (string, bool) {
+	upper := strings.ToUpper(cmd)
+	switch upper {
+	case "EVAL", "EVALSHA":
+		if len(args) > 2 {
+			if n, _ := strconv.Atoi(argString(args[1])); n > 0 {
+				return argString(args[2]), true
+			}
+		}
+		return "", false
+	case "XGROUP", "XINFO", "OBJECT", "MEMORY": //子命令之后为Key
+		if len(args) > 1 {
+			return argString(args[1]), true
+		}
+		return "", false
+	case "XREAD", "XREADGROUP":
+		for i, arg := range args {
+			if strings.EqualFold(argString(arg), "STREAMS") && i+1 < len(args) {
+				return argString(args[i+1]), true
+			}
+		}
+		return "", false
+	}
+	if clusterNoKeyCommands[upper] || len(args) < 1 {
+		return "", false
+	}
+	return argString(args[0]), true
+}
+
+//按槽位拆分多Key命令的Key，返回每组Key在args中的位置；不是多Key命令时返回nil
+func splitKeys(cmd string, args []interface{}) [][]int {
+	step, ok := clusterMultiKeyCommands[strings.ToUpper(cmd)]
+	if !ok || len(args) <= step {
+		return nil
+	}
+	var groups [][]int
+	index := make(map[int]int) //槽位对应的分组
+	for k := 0; k+step <= len(args); k += step {
+		slot := keySlot(argString(args[k]))
+		g, ok := index[slot]
+		if !ok {
+			g = len(groups)
+			index[slot] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], k)
+	}
+	return groups
+}
+
+//解析重定向错误：MOVED/ASK 槽位 地址
+func parseRedirect(err error) (kind string, addr string, slot int) {
+	e, ok := err.(redis.Error)
+	if !ok {
+		return
+	}
+	fields := strings.Fields(string(e))
+	if len(fields) != 3 || fields[0] != "MOVED" && fields[0] != "ASK" {
+		return
+	}
+	slot, _ = strconv.Atoi(fields[1])
+	return fields[0], fields[2], slot
+}
+
+//参数转为字符串
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(arg)
+}
+
+func (cc *clusterConn) Close() error {
+	var err error
+	if cc.pinned != nil { //缓存池的链接关闭时自动放弃未完成的事务
+		err = cc.pinned.Close()
+		cc.pinned = nil
+	}
+	cc.pending, cc.replies, cc.multi, cc.closed = nil, nil, false, true
+	return err
+}
+
+func (cc *clusterConn) Err() error {
+	if cc.closed {
+		return errClusterConnClosed
+	}
+	if cc.pinned != nil {
+		return cc.pinned.Err()
+	}
+	return nil
+}
+
+func (cc *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cc.closed {
+		return nil, errClusterConnClosed
+	}
+	if err := cc.route(cmd, args); err != nil {
+		return nil, err
+	}
+	if cc.pinned == nil {
+		if err := cc.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	if cc.pinned != nil {
+		cc.replies = nil
+		return cc.pinned.Do(cmd, args...)
+	}
+	replies := cc.replies
+	cc.replies = nil
+	if cmd == "" { //返回所有待接收的返回值
+		if len(replies) < 1 {
+			return nil, nil
+		}
+		vals := make([]interface{}, len(replies))
+		for i, rp := range replies {
+			vals[i] = rp.val
+			if e, ok := rp.err.(redis.Error); ok {
+				vals[i] = e
+			}
+		}
+		return vals, nil
+	}
+	return cc.c.do(cmd, args...)
+}
+
+func (cc *clusterConn) Send(cmd string, args ...interface{}) error {
+	if cc.closed {
+		return errClusterConnClosed
+	}
+	if err := cc.route(cmd, args); err != nil {
+		return err
+	}
+	if cc.pinned != nil {
+		return cc.pinned.Send(cmd, args...)
+	}
+	cc.pending = append(cc.pending, clusterCmd{name: cmd, args: args})
+	return nil
+}
+
+func (cc *clusterConn) Flush() error {
+	if cc.closed {
+		return errClusterConnClosed
+	}
+	if cc.multi && cc.pinned == nil && len(cc.pending) > 0 { //事务中没有Key时使用任意节点
+		if err := cc.pin(cc.c.anyAddr()); err != nil {
+			return err
+		}
+	}
+	if cc.pinned != nil {
+		return cc.pinned.Flush()
+	}
+	if len(cc.pending) > 0 {
+		cc.replies = append(cc.replies, cc.c.pipeline(cc.pending)...)
+		cc.pending = nil
+	}
+	return nil
+}
+
+func (cc *clusterConn) Receive() (interface{}, error) {
+	if cc.closed {
+		return nil, errClusterConnClosed
+	}
+	if len(cc.pending) > 0 && cc.pinned == nil {
+		if err := cc.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	if len(cc.replies) > 0 {
+		rp := cc.replies[0]
+		cc.replies = cc.replies[1:]
+		return rp.val, rp.err
+	}
+	if cc.pinned != nil {
+		return cc.pinned.Receive()
+	}
+	return nil, errClusterNoReply
+}
+
+//事务的命令固定在一个节点执行：WATCH或MULTI后先执行之前的命令，再按第一个有Key的命令选择节点
+func (cc *clusterConn) route(cmd string, args []interface{}) error {
+	if cc.pinned != nil {
+		return nil
+	}
+	upper := strings.ToUpper(cmd)
+	if !cc.multi && (upper == "WATCH" || upper == "MULTI") {
+		if err := cc.Flush(); err != nil {
+			return err
+		}
+		cc.multi = true
+	}
+	if !cc.multi {
+		return nil
+	}
+	if _, ok := commandKey(cmd, args); ok || upper == "EXEC" || upper == "DISCARD" || upper == "UNWATCH" {
+		return cc.pin(cc.c.nodeFor(cmd, args))
+	}
+	return nil
+}
+
+//固定使用节点的链接，并发送事务中已缓存的命令
+func (cc *clusterConn) pin(addr string) error {
+	conn, err := cc.c.nodeConn(addr)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cc.pending {
+		if err = conn.Send(cmd.name, cmd.args...); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	cc.pending = nil
+	cc.pinned = conn
+	return nil
+}
@@ -0,0 +1,301 @@
+package Cache
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+//槽位与Redis Cluster规范（CRC16 XMODEM对16384取模）一致，{}中的hashtag决定槽位
+func TestKeySlot(t *testing.T) {
+	cases := map[string]int{
+		"":                     0,
+		"123456789":            12739, //规范中的校验值0x31C3
+		"foo":                  12182,
+		"bar":                  5061,
+		"{user1000}.following": keySlot("user1000"),
+		"{user1000}.followers": keySlot("user1000"),
+		"foo{}{bar}":           keySlot("foo{}{bar}"),
+		"foo{{bar}}zap":        keySlot("{bar"),
+		"foo{bar}{zap}":        keySlot("bar"),
+		"{}":                   keySlot("{}"),
+	}
+	for key, want := range cases {
+		if got := keySlot(key); got != want {
+			t.Errorf("%q的槽位应为%d，实际为%d", key, want, got)
+		}
+	}
+	if keySlot("foo{}{bar}") == keySlot("bar") {
+		t.Error("空的hashtag应使用整个Key计算槽位")
+	}
+}
+
+//获取命令的Key：脚本取第一个KEYS，Stream读取取STREAMS后的第一个Key，子命令取其后的Key
+func TestCommandKey(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		args []interface{}
+		key  string
+		ok   bool
+	}{
+		{"GET", []interface{}{"a"}, "a", true},
+		{"set", []interface{}{[]byte("b"), 1}, "b", true},
+		{"EVAL", []interface{}{"return 1", 1, "k1"}, "k1", true},
+		{"EVALSHA", []interface{}{"sha", "2", "k1", "k2"}, "k1", true},
+		{"EVAL", []interface{}{"return 1", 0}, "", false},
+		{"EVAL", []interface{}{"return 1", 0, "arg"}, "", false},
+		{"XREADGROUP", []interface{}{"GROUP", "g", "c", "COUNT", 1, "STREAMS", "s1", "s2", ">", ">"}, "s1", true},
+		{"xread", []interface{}{"BLOCK", 0, "streams", "s", "$"}, "s", true},
+		{"XREAD", []interface{}{"COUNT", 1}, "", false},
+		{"XGROUP", []interface{}{"CREATE", "s", "g", "$"}, "s", true},
+		{"XINFO", []interface{}{"STREAM", "s"}, "s", true},
+		{"XGROUP", []interface{}{"HELP"}, "", false},
+		{"PING", nil, "", false},
+		{"KEYS", []interface{}{"*"}, "", false},
+		{"DEL", nil, "", false},
+	}
+	for _, c := range cases {
+		key, ok := commandKey(c.cmd, c.args)
+		if key != c.key || ok != c.ok {
+			t.Errorf("%s %v: Key应为%q %v，实际为%q %v", c.cmd, c.args, c.key, c.ok, key, ok)
+		}
+	}
+}
+
+//多Key命令按槽位分组，同一槽位的Key在同一组，保持原顺序
+func TestSplitKeys(t *testing.T) {
+	cases := []struct {
+		cmd  string
+		args []interface{}
+		want [][]int
+	}{
+		{"MGET", []interface{}{"a", "b", "{a}x", "c"}, [][]int{{0, 2}, {1}, {3}}},
+		{"mset", []interface{}{"a", 1, "b", 2, "{a}x", 3}, [][]int{{0, 4}, {2}}},
+		{"DEL", []interface{}{"{u}1", "{u}2"}, [][]int{{0, 1}}},
+		{"MGET", []interface{}{"a"}, nil},
+		{"MSET", []interface{}{"a", 1}, nil},
+		{"GET", []interface{}{"a", "b"}, nil},
+	}
+	for _, c := range cases {
+		if got := splitKeys(c.cmd, c.args); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %v: 分组应为%v，实际为%v", c.cmd, c.args, c.want, got)
+		}
+	}
+}
+
+//解析MOVED/ASK重定向，其他错误返回空
+func TestParseRedirect(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind string
+		addr string
+		slot int
+	}{
+		{redis.Error("MOVED 3999 127.0.0.1:6381"), "MOVED", "127.0.0.1:6381", 3999},
+		{redis.Error("ASK 12182 10.0.0.2:7000"), "ASK", "10.0.0.2:7000", 12182},
+		{redis.Error("ERR unknown command"), "", "", 0},
+		{redis.Error("MOVED 3999"), "", "", 0},
+		{errors.New("MOVED 3999 127.0.0.1:6381"), "", "", 0},
+		{nil, "", "", 0},
+	}
+	for _, c := range cases {
+		kind, addr, slot := parseRedirect(c.err)
+		if kind != c.kind || addr != c.addr || slot != c.slot {
+			t.Errorf("%v: 解析结果错误：%q %q %d", c.err, kind, addr, slot)
+		}
+	}
+}
+
+//拆分执行的MGET按原顺序返回，DEL返回各节点的数量之和；ASK时向目标节点发送ASKING后重试
+func TestClusterDoMulti(t *testing.T) {
+	c, a, b := newFakeCluster("b")
+	vals, err := redis.Strings(c.do("MGET", "a", "b", "c", "{b}x"))
+	if err != nil || !reflect.DeepEqual(vals, []string{"A:a", "B:b", "A:c", "B:{b}x"}) {
+		t.Fatalf("MGET结果错误：%v %v", vals, err)
+	}
+	if n, err := redis.Int64(c.do("DEL", "a", "b", "c")); err != nil || n != 3 {
+		t.Errorf("DEL结果错误：%d %v", n, err)
+	}
+	if v, err := redis.String(c.do("MSET", "a", 1, "b", 2)); err != nil || v != "OK" {
+		t.Errorf("MSET结果错误：%v %v", v, err)
+	}
+	a.ask["moving"] = "B"
+	if v, err := redis.String(c.do("GET", "moving")); err != nil || v != "B:moving" {
+		t.Errorf("ASK重定向结果错误：%v %v", v, err)
+	}
+	if got := b.log[len(b.log)-2:]; !reflect.DeepEqual(got, []string{"1 ASKING", "1 GET moving"}) {
+		t.Errorf("ASK时应先发送ASKING：%v", got)
+	}
+}
+
+//管道中的命令按节点分组执行，返回值按发送顺序接收
+func TestClusterConnReplyOrder(t *testing.T) {
+	c, a, b := newFakeCluster("b")
+	cc := c.conn()
+	for _, key := range []string{"a", "b", "c", "{b}y"} {
+		cc.Send("GET", key)
+	}
+	if err := cc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"A:a", "B:b", "A:c", "B:{b}y"} {
+		if v, err := redis.String(cc.Receive()); err != nil || v != want {
+			t.Errorf("返回值顺序错误，应为%s，实际为%v %v", want, v, err)
+		}
+	}
+	if _, err := cc.Receive(); err != errClusterNoReply {
+		t.Errorf("没有待接收的返回值时应返回错误：%v", err)
+	}
+	if len(a.log) != 2 || len(b.log) != 2 {
+		t.Errorf("命令应按节点分组发送：%v %v", a.log, b.log)
+	}
+	cc.Close()
+	if _, err := cc.Do("GET", "a"); err != errClusterConnClosed {
+		t.Errorf("关闭后应返回errClusterConnClosed：%v", err)
+	}
+}
+
+//MULTI后固定使用第一个Key所在节点的链接，MULTI前的命令先执行
+func TestClusterConnMultiPin(t *testing.T) {
+	c, a, b := newFakeCluster("b")
+	cc := c.conn()
+	cc.Send("GET", "a")
+	cc.Send("MULTI")
+	cc.Send("INCR", "b")
+	cc.Send("INCR", "{b}x")
+	reply, err := redis.Values(cc.Do("EXEC"))
+	if err != nil || len(reply) != 2 {
+		t.Fatalf("EXEC结果错误：%v %v", reply, err)
+	}
+	want := []string{"1 MULTI", "1 INCR b", "1 INCR {b}x", "1 EXEC"}
+	if !reflect.DeepEqual(b.log, want) {
+		t.Errorf("事务应在同一节点的同一链接执行：%v", b.log)
+	}
+	if !reflect.DeepEqual(a.log, []string{"1 GET a"}) {
+		t.Errorf("MULTI前的命令应先在对应节点执行：%v", a.log)
+	}
+	if _, err := cc.Receive(); err == nil {
+		t.Error("Do应丢弃之前未接收的返回值")
+	}
+	cc.Close()
+}
+
+//创建槽位全部在节点A的集群，moveKeys所在的槽位在节点B
+func newFakeCluster(moveKeys ...string) (*redisCluster, *fakeNode, *fakeNode) {
+	a, b := newFakeNode("A"), newFakeNode("B")
+	c := &redisCluster{
+		r:        &RedisModel{},
+		settings: &RedisSettings{ClusterNodes: []string{"A", "B"}},
+		slots:    make([]string, clusterSlots),
+		pools:    map[string]*redis.Pool{"A": a.pool(), "B": b.pool()},
+	}
+	for i := range c.slots {
+		c.slots[i] = "A"
+	}
+	for _, key := range moveKeys {
+		c.slots[keySlot(key)] = "B"
+	}
+	return c, a, b
+}
+
+//模拟的集群节点，记录每个链接执行的命令（链接序号 命令 参数）
+type fakeNode struct {
+	name  string
+	conns int
+	log   []string
+	ask   map[string]string //Key对应的ASK重定向节点
+}
+
+type fakeNodeConn struct {
+	n       *fakeNode
+	id      int
+	asking  bool
+	multi   int
+	pending []fakeReply
+}
+
+func newFakeNode(name string) *fakeNode {
+	return &fakeNode{name: name, ask: make(map[string]string)}
+}
+
+func (n *fakeNode) pool() *redis.Pool {
+	return &redis.Pool{MaxIdle: 1, Dial: func() (redis.Conn, error) {
+		n.conns++
+		return &fakeNodeConn{n: n, id: n.conns}, nil
+	}}
+}
+
+func (c *fakeNodeConn) exec(cmd string, args []interface{}) (interface{}, error) {
+	cmd = strings.ToUpper(cmd)
+	var s []string
+	for _, arg := range args {
+		s = append(s, argString(arg))
+	}
+	c.n.log = append(c.n.log, strings.TrimSpace(fmt.Sprintf("%d %s %s", c.id, cmd, strings.Join(s, " "))))
+	if key, ok := commandKey(cmd, args); ok && !c.asking {
+		if target := c.n.ask[argString(key)]; target != "" {
+			return nil, redis.Error(fmt.Sprintf("ASK %d %s", keySlot(key), target))
+		}
+	}
+	c.asking = false
+	switch cmd {
+	case "ASKING":
+		c.asking = true
+		return "OK", nil
+	case "MULTI":
+		c.multi = 0
+		return "OK", nil
+	case "EXEC":
+		vals := make([]interface{}, c.multi)
+		for i := range vals {
+			vals[i] = int64(i + 1)
+		}
+		return vals, nil
+	case "INCR":
+		c.multi++
+		return "QUEUED", nil
+	case "GET":
+		return c.n.name + ":" + s[0], nil
+	case "MGET":
+		vals := make([]interface{}, len(s))
+		for i, key := range s {
+			vals[i] = []byte(c.n.name + ":" + key)
+		}
+		return vals, nil
+	case "DEL":
+		return int64(len(s)), nil
+	}
+	return "OK", nil
+}
+
+func (c *fakeNodeConn) Close() error { return nil }
+func (c *fakeNodeConn) Err() error   { return nil }
+func (c *fakeNodeConn) Flush() error { return nil }
+func (c *fakeNodeConn) Send(cmd string, args ...interface{}) error {
+	val, err := c.exec(cmd, args)
+	c.pending = append(c.pending, fakeReply{val, err})
+	return nil
+}
+func (c *fakeNodeConn) Receive() (interface{}, error) {
+	if len(c.pending) < 1 {
+		return nil, errClusterNoReply
+	}
+	r := c.pending[0]
+	c.pending = c.pending[1:]
+	return r.val, r.err
+}
+func (c *fakeNodeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "" {
+		c.Send(cmd, args...)
+	}
+	var val interface{}
+	var err error
+	for len(c.pending) > 0 {
+		val, err = c.Receive()
+	}
+	return val, err
+}
@@ -0,0 +1,438 @@
+/*
+	缓存数据的序列化方式，支持JSON、gob及msgpack，可使用snappy压缩
+	msgpack通过JSON的数据结构转换，struct字段使用json标签映射，[]byte按JSON规则编码为base64字符串
+	使用方法：
+	data, err := Cache.MsgpackCodec.Marshal(user)
+	err = Cache.MsgpackCodec.Unmarshal(data, &user)
+	codec := Cache.SnappyCodec(Cache.JSONCodec) //JSON序列化后压缩
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/misgo/aresgo/router/klauspost/compress/snappy"
+)
+
+const (
+	CodecJSON    = "json"
+	CodecGob     = "gob"
+	CodecMsgpack = "msgpack"
+
+	msgpackMaxDepth = 10000 //解码时数组及map的最大嵌套层数
+)
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	GobCodec     Codec = gobCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+
+	DefaultCodec = JSONCodec //未指定序列化方式时使用
+
+	errMsgpackShort = errors.New("msgpack数据不完整")
+	errMsgpackDepth = errors.New("msgpack数据嵌套层数过多")
+)
+
+type (
+	//序列化接口
+	Codec interface {
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	jsonCodec    struct{}
+	gobCodec     struct{}
+	msgpackCodec struct{}
+
+	//snappy压缩
+	snappyCodec struct {
+		codec Codec
+	}
+
+	//msgpack解码器
+	msgpackDecoder struct {
+		data  []byte
+		pos   int
+		depth int //当前的嵌套层数
+	}
+)
+
+//根据名称获取序列化方式
+//@param name 名称：json/gob/msgpack，为空时使用DefaultCodec
+//@param compress 是否使用snappy压缩
+func NewCodec(name string, compress bool) (Codec, error) {
+	var codec Codec
+	switch strings.ToLower(name) {
+	case "":
+		codec = DefaultCodec
+	case CodecJSON:
+		codec = JSONCodec
+	case CodecGob:
+		codec = GobCodec
+	case CodecMsgpack:
+		codec = MsgpackCodec
+	default:
+		return nil, fmt.Errorf("不支持的序列化方式[%s]", name)
+	}
+	if compress {
+		codec = SnappyCodec(codec)
+	}
+	return codec, nil
+}
+
+//序列化后使用snappy压缩
+func SnappyCodec(codec Codec) Codec {
+	return snappyCodec{codec: codec}
+}
+
+func (c snappyCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
+
+func (c snappyCodec) Unmarshal(data []byte, v interface{}) error {
+	raw, err := snappy.Decode(nil, data)
+	if err != nil {
+		return err
+	}
+	return c.codec.Unmarshal(raw, v)
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var tree interface{}
+	if err = d.Decode(&tree); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = msgpackEncode(&buf, tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	d := &msgpackDecoder{data: data}
+	tree, err := d.decode()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return errors.New("msgpack数据末尾有多余的字节")
+	}
+	js, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, v)
+}
+
+//将JSON数据结构编码为msgpack
+func msgpackEncode(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if val {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			msgpackInt(buf, n)
+		} else if u, err := strconv.ParseUint(string(val), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, u)
+		} else {
+			f, err := val.Float64()
+			if err != nil {
+				return err
+			}
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		msgpackHeader(buf, len(val), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(val)
+	case []interface{}:
+		msgpackHeader(buf, len(val), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range val {
+			if err := msgpackEncode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		msgpackHeader(buf, len(val), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			msgpackEncode(buf, k)
+			if err := msgpackEncode(buf, val[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack不支持的数据类型[%T]", v)
+	}
+	return nil
+}
+
+//编码整数，使用最短的格式
+func msgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n >= -32 && n < 0:
+		buf.WriteByte(byte(int8(n)))
+	case n > 0 && n <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(n))
+	case n > 0 && n <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n > 0 && n <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	case n > 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(n))
+	case n >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+//编码字符串、数组、map的类型及长度
+//@param fix 短格式的类型前缀，fixMax为短格式的最大长度
+//@param t8 8位长度的类型（数组及map没有此格式时为0）
+func msgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, t8 byte, t16 byte, t32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case t8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(t8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(t16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(t32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+//解码为JSON数据结构
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	t := b[0]
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xe0 == 0xa0:
+		return d.str(int(t & 0x1f))
+	case t&0xf0 == 0x90:
+		return d.array(int(t & 0x0f))
+	case t&0xf0 == 0x80:
+		return d.object(int(t & 0x0f))
+	}
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: //bin，按JSON规则转为base64字符串
+		n, err := d.length(t - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (t - 0xcc))
+	case 0xd0:
+		u, err := d.uint(1)
+		return int64(int8(u)), err
+	case 0xd1:
+		u, err := d.uint(2)
+		return int64(int16(u)), err
+	case 0xd2:
+		u, err := d.uint(4)
+		return int64(int32(u)), err
+	case 0xd3:
+		u, err := d.uint(8)
+		return int64(u), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(t - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(t - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n)
+	case 0xde, 0xdf:
+		n, err := d.length(t - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.object(n)
+	}
+	return nil, fmt.Errorf("msgpack不支持的数据类型[0x%x]", t)
+}
+
+//读取长度，size为0、1、2时分别为8、16、32位
+func (d *msgpackDecoder) length(size byte) (int, error) {
+	u, err := d.uint(1 << size)
+	return int(u), err
+}
+
+//读取大端序无符号整数
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, v := range b {
+		u = u<<8 | uint64(v)
+	}
+	return u, nil
+}
+
+func (d *msgpackDecoder) str(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+//长度来自数据，每个元素至少占1个字节，超过剩余字节数时数据不完整，避免按错误的长度预分配内存
+func (d *msgpackDecoder) array(n int) (interface{}, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	if d.depth++; d.depth > msgpackMaxDepth {
+		return nil, errMsgpackDepth
+	}
+	defer func() { d.depth-- }()
+	list := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+//map的Key不是字符串时转为字符串；每对Key和值至少占2个字节
+func (d *msgpackDecoder) object(n int) (interface{}, error) {
+	if n < 0 || n > (len(d.data)-d.pos)/2 {
+		return nil, errMsgpackShort
+	}
+	if d.depth++; d.depth > msgpackMaxDepth {
+		return nil, errMsgpackDepth
+	}
+	defer func() { d.depth-- }()
+	obj := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		obj[key] = v
+	}
+	return obj, nil
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
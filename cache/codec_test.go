@@ -0,0 +1,175 @@
+package Cache
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type codecUser struct {
+	Id     int64             `json:"id"`
+	Name   string            `json:"name"`
+	Score  float64           `json:"score"`
+	Active bool              `json:"active"`
+	Tags   []string          `json:"tags"`
+	Attrs  map[string]string `json:"attrs"`
+	Data   []byte            `json:"data"`
+	Parent *codecUser        `json:"parent"`
+}
+
+//各数据类型及各长度格式编码后解码结果一致
+func TestMsgpackRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		v      interface{}
+		header byte //编码后的类型字节
+	}{
+		{"nil", (*codecUser)(nil), 0xc0},
+		{"false", false, 0xc2},
+		{"true", true, 0xc3},
+		{"positive fixint", int64(127), 0x7f},
+		{"negative fixint", int64(-32), 0xe0},
+		{"uint8", int64(255), 0xcc},
+		{"uint16", int64(65535), 0xcd},
+		{"uint32", int64(math.MaxUint32), 0xce},
+		{"uint64", uint64(math.MaxUint64), 0xcf},
+		{"max int64", int64(math.MaxInt64), 0xcf},
+		{"int8", int64(-128), 0xd0},
+		{"int16", int64(-32768), 0xd1},
+		{"int32", int64(math.MinInt32), 0xd2},
+		{"int64", int64(math.MinInt64), 0xd3},
+		{"float64", 3.25, 0xcb},
+		{"fixstr", strings.Repeat("a", 31), 0xbf},
+		{"str8", strings.Repeat("a", 255), 0xd9},
+		{"str16", strings.Repeat("中", 100), 0xda},
+		{"str32", strings.Repeat("a", 70000), 0xdb},
+		{"fixarray", make([]int64, 15), 0x9f},
+		{"array16", make([]int64, 16), 0xdc},
+		{"array32", make([]int64, 70000), 0xdd},
+		{"fixmap", sizedMap(15), 0x8f},
+		{"map16", sizedMap(16), 0xde},
+		{"map32", sizedMap(70000), 0xdf},
+		{"struct", &codecUser{
+			Id: -1, Name: "hyperion", Score: 0.5, Active: true,
+			Tags: []string{"a", ""}, Attrs: map[string]string{"k": "v"}, Data: []byte{0, 1, 255},
+			Parent: &codecUser{Id: 1 << 40},
+		}, 0x88},
+	}
+	for _, c := range cases {
+		data, err := MsgpackCodec.Marshal(c.v)
+		if err != nil {
+			t.Fatalf("%s: 编码失败：%v", c.name, err)
+		}
+		if data[0] != c.header {
+			t.Errorf("%s: 类型字节应为0x%x，实际为0x%x", c.name, c.header, data[0])
+		}
+		got := reflect.New(reflect.TypeOf(c.v))
+		if err = MsgpackCodec.Unmarshal(data, got.Interface()); err != nil {
+			t.Fatalf("%s: 解码失败：%v", c.name, err)
+		}
+		if !reflect.DeepEqual(got.Elem().Interface(), c.v) {
+			t.Errorf("%s: 解码结果不一致", c.name)
+		}
+	}
+}
+
+//其他编码器生成的格式（float32、bin、非字符串的map Key）
+func TestMsgpackDecodeFormats(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		v    interface{}
+		want interface{}
+	}{
+		{"float32", []byte{0xca, 0x3f, 0xc0, 0, 0}, new(float64), 1.5},
+		{"bin8", []byte{0xc4, 2, 'h', 'i'}, new([]byte), []byte("hi")},
+		{"bin16", []byte{0xc5, 0, 1, 'x'}, new([]byte), []byte("x")},
+		{"int key", []byte{0x81, 0x01, 0xa1, 'a'}, new(map[string]string), map[string]string{"1": "a"}},
+	}
+	for _, c := range cases {
+		if err := MsgpackCodec.Unmarshal(c.data, c.v); err != nil {
+			t.Fatalf("%s: 解码失败：%v", c.name, err)
+		}
+		if got := reflect.ValueOf(c.v).Elem().Interface(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: 解码结果错误：%v", c.name, got)
+		}
+	}
+}
+
+//截断的数据在任意位置都返回错误
+func TestMsgpackTruncated(t *testing.T) {
+	data, err := MsgpackCodec.Marshal(&codecUser{Name: "hyperion", Tags: []string{"a"}, Data: []byte("x"), Parent: &codecUser{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i++ {
+		var u codecUser
+		if err := MsgpackCodec.Unmarshal(data[:i], &u); err == nil {
+			t.Fatalf("截断到%d字节时应返回错误", i)
+		}
+	}
+}
+
+//损坏的数据返回错误，数据中的长度不会导致按其预分配内存
+func TestMsgpackCorrupt(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"unknown type", []byte{0xc1}},
+		{"trailing bytes", []byte{0x01, 0x02}},
+		{"array32 huge length", []byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"map32 huge length", []byte{0xdf, 0xff, 0xff, 0xff, 0xff, 0x01, 0x01}},
+		{"array16 short", []byte{0xdc, 0x00, 0x03, 0x01, 0x02}},
+		{"map short", []byte{0x82, 0xa1, 'a', 0x01}},
+		{"str32 huge length", []byte{0xdb, 0xff, 0xff, 0xff, 0xff, 'a'}},
+		{"bin32 huge length", []byte{0xc6, 0xff, 0xff, 0xff, 0xff, 'a'}},
+		{"too deep", bytes.Repeat([]byte{0x91}, msgpackMaxDepth+1)},
+	}
+	for _, c := range cases {
+		var v interface{}
+		allocs := testing.AllocsPerRun(1, func() {
+			if err := MsgpackCodec.Unmarshal(c.data, &v); err == nil {
+				t.Errorf("%s: 应返回错误", c.name)
+			}
+		})
+		if c.name != "too deep" && allocs > 10 {
+			t.Errorf("%s: 内存分配次数过多：%v", c.name, allocs)
+		}
+	}
+}
+
+//按名称获取序列化方式，各方式及压缩后编码解码结果一致
+func TestNewCodec(t *testing.T) {
+	u := codecUser{Id: 1, Name: "hyperion", Tags: []string{"a"}}
+	for _, name := range []string{"", CodecJSON, CodecGob, "MsgPack"} {
+		for _, compress := range []bool{false, true} {
+			codec, err := NewCodec(name, compress)
+			if err != nil {
+				t.Fatalf("%q: %v", name, err)
+			}
+			data, err := codec.Marshal(&u)
+			if err != nil {
+				t.Fatalf("%q %v: 编码失败：%v", name, compress, err)
+			}
+			var got codecUser
+			if err = codec.Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, u) {
+				t.Errorf("%q %v: 解码结果错误：%+v %v", name, compress, got, err)
+			}
+		}
+	}
+	if _, err := NewCodec("xml", false); err == nil {
+		t.Error("不支持的序列化方式应返回错误")
+	}
+}
+
+func sizedMap(n int) map[string]int64 {
+	m := make(map[string]int64, n)
+	for i := 0; i < n; i++ {
+		m[strconv.Itoa(i)] = int64(i)
+	}
+	return m
+}
@@ -0,0 +1,339 @@
+/*
+	Redis分布式锁，使用SET NX PX加随机令牌加锁，Lua脚本比较令牌后删除，持有期间自动续期
+	Redlock模式在多个独立的Redis实例上加锁，超过半数实例加锁成功且未超时时视为获取成功
+	使用方法：
+	lock, err := r.Lock("cron:report", 10*time.Second)               //不等待，锁被占用时返回ErrLockNotAcquired
+	lock, err := r.Lock("cron:report", 10*time.Second, 3*time.Second) //最多等待3秒
+	if err == nil {
+		defer lock.Unlock()
+		select {
+		case <-lock.Lost(): //续期失败，锁已失效
+		case <-done:
+		}
+	}
+	rl := Cache.NewRedlock(r1, r2, r3)
+	lock, err := rl.Lock("order:1001", 5*time.Second)
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+var (
+	LockRetryMin   = 50 * time.Millisecond //等待锁时的最小重试间隔
+	LockRetryMax   = time.Second           //等待锁时的最大重试间隔
+	LockClockDrift = 0.01                  //Redlock的时钟漂移系数，锁的有效时间需扣除ttl*LockClockDrift
+	LockMinTTL     = 30 * time.Millisecond //锁的最小有效时间，自动续期间隔为ttl/3
+
+	ErrLockNotAcquired = errors.New("获取锁失败：锁已被其他客户端持有")
+	ErrLockNotHeld     = errors.New("锁已失效或被其他客户端持有")
+	ErrLockTTLTooShort = errors.New("锁的有效时间不能小于LockMinTTL")
+
+	//令牌一致时删除
+	unlockScript = redis.NewScript(1, `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end return 0`)
+	//令牌一致时续期
+	refreshScript = redis.NewScript(1, `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end return 0`)
+)
+
+type (
+	//Redlock，在多个独立的Redis实例（主库）上加锁
+	Redlock struct {
+		models []*RedisModel
+	}
+
+	//已获取的锁
+	Lock struct {
+		key    string
+		token  string
+		models []*RedisModel
+
+		mu         sync.Mutex
+		ttl        time.Duration
+		lastExtend time.Time //最后一次加锁或续期成功的时间
+		released   bool
+		stopOnce   sync.Once
+		stop       chan struct{}
+		lost       chan struct{}
+	}
+)
+
+//获取锁，获取成功后自动续期直到调用Unlock
+//@param key 锁的Key（自动添加Key前缀）
+//@param ttl 锁的有效时间，持有锁的进程异常退出时锁在ttl后失效
+//@param wait 等待锁的最长时间（可选），不传或小于等于0时不等待
+func (r *RedisModel) Lock(key string, ttl time.Duration, wait ...time.Duration) (*Lock, error) {
+	return acquireLock([]*RedisModel{r}, key, ttl, wait)
+}
+
+//创建Redlock
+//@param models 独立的Redis实例，建议为奇数个
+func NewRedlock(models ...*RedisModel) *Redlock {
+	return &Redlock{models: models}
+}
+
+//在超过半数的实例上获取锁，获取成功后自动续期直到调用Unlock
+//@param key 锁的Key（自动添加各实例的Key前缀）
+//@param ttl 锁的有效时间
+//@param wait 等待锁的最长时间（可选），不传或小于等于0时不等待
+func (rl *Redlock) Lock(key string, ttl time.Duration, wait ...time.Duration) (*Lock, error) {
+	if len(rl.models) < 1 {
+		return nil, errors.New("Redlock未设置Redis实例")
+	}
+	return acquireLock(rl.models, key, ttl, wait)
+}
+
+//获取锁，失败时按指数退避（加随机抖动）重试直到超过等待时间
+func acquireLock(models []*RedisModel, key string, ttl time.Duration, wait []time.Duration) (*Lock, error) {
+	if key == "" {
+		return nil, errors.New("锁的Key不能为空")
+	}
+	if ttl < LockMinTTL {
+		return nil, ErrLockTTLTooShort
+	}
+	token, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{key: key, token: token, models: models, ttl: ttl}
+	var deadline time.Time
+	if len(wait) > 0 && wait[0] > 0 {
+		deadline = time.Now().Add(wait[0])
+	}
+	backoff := LockRetryMin
+	for {
+		ok, err := l.tryAcquire()
+		if ok {
+			l.stop = make(chan struct{})
+			l.lost = make(chan struct{})
+			go l.renew()
+			return l, nil
+		}
+		remain := time.Until(deadline)
+		if remain <= 0 {
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrLockNotAcquired
+		}
+		sleep := backoff/2 + randDuration(backoff/2)
+		if sleep > remain {
+			sleep = remain
+		}
+		time.Sleep(sleep)
+		if backoff *= 2; backoff > LockRetryMax {
+			backoff = LockRetryMax
+		}
+	}
+}
+
+//在所有实例上尝试加锁，成功数未超过半数或已超过有效时间时释放已加的锁
+func (l *Lock) tryAcquire() (bool, error) {
+	start := time.Now()
+	ms := l.ttl.Milliseconds()
+	n, err := l.each(func(r *RedisModel) (bool, error) {
+		c, err := r.getConn(r.redisWriter, r.writerSettings)
+		if err != nil {
+			return false, err
+		}
+		defer c.Close()
+		reply, err := c.Do("SET", r.GenerateKey(l.key), l.token, "PX", ms, "NX")
+		return reply != nil, err
+	})
+	if n >= l.quorum() && l.validity(start) > 0 {
+		l.lastExtend = start
+		return true, nil
+	}
+	if n > 0 {
+		l.each(l.unlockOne)
+	}
+	return false, err
+}
+
+//释放锁并停止自动续期，锁已失效时返回ErrLockNotHeld
+func (l *Lock) Unlock() error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrLockNotHeld
+	}
+	l.released = true
+	n, err := l.each(l.unlockOne)
+	if n >= l.quorum() {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrLockNotHeld
+}
+
+//手动续期，锁已失效时返回ErrLockNotHeld
+//@param ttl 新的有效时间（可选），不传时使用加锁时的有效时间，之后的自动续期也使用新的有效时间；小于LockMinTTL时返回ErrLockTTLTooShort
+func (l *Lock) Refresh(ttl ...time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return ErrLockNotHeld
+	}
+	if len(ttl) > 0 {
+		if ttl[0] < LockMinTTL {
+			return ErrLockTTLTooShort
+		}
+		l.ttl = ttl[0]
+	}
+	start := time.Now()
+	ms := l.ttl.Milliseconds()
+	n, err := l.each(func(r *RedisModel) (bool, error) {
+		c, err := r.getConn(r.redisWriter, r.writerSettings)
+		if err != nil {
+			return false, err
+		}
+		defer c.Close()
+		res, err := redis.Int(refreshScript.Do(c, r.GenerateKey(l.key), l.token, ms))
+		return res == 1, err
+	})
+	if n >= l.quorum() && l.validity(start) > 0 {
+		l.lastExtend = start
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return ErrLockNotHeld
+}
+
+//续期失败导致锁失效时关闭此通道
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+//锁的Key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+//自动续期，每ttl/3续期一次；锁被其他客户端持有或超过有效时间仍未续期成功时视为失效
+func (l *Lock) renew() {
+	for {
+		l.mu.Lock()
+		interval := renewInterval(l.ttl)
+		l.mu.Unlock()
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(interval):
+		}
+		err := l.Refresh()
+		if err == nil {
+			continue
+		}
+		l.mu.Lock()
+		expired := l.released || time.Since(l.lastExtend) >= l.ttl
+		l.mu.Unlock()
+		if err == ErrLockNotHeld || expired {
+			select {
+			case <-l.stop: //已调用Unlock
+			default:
+				close(l.lost)
+			}
+			return
+		}
+	}
+}
+
+//自动续期的间隔，为有效时间的1/3，最小1毫秒，避免有效时间过短时空转
+func renewInterval(ttl time.Duration) time.Duration {
+	if interval := ttl / 3; interval >= time.Millisecond {
+		return interval
+	}
+	return time.Millisecond
+}
+
+//在单个实例上释放锁
+func (l *Lock) unlockOne(r *RedisModel) (bool, error) {
+	c, err := r.getConn(r.redisWriter, r.writerSettings)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+	res, err := redis.Int(unlockScript.Do(c, r.GenerateKey(l.key), l.token))
+	return res == 1, err
+}
+
+//在所有实例上并发执行，返回成功的个数及第一个错误
+func (l *Lock) each(fn func(r *RedisModel) (bool, error)) (int, error) {
+	if len(l.models) == 1 {
+		ok, err := fn(l.models[0])
+		if ok {
+			return 1, err
+		}
+		return 0, err
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		n        int
+		firstErr error
+	)
+	for _, r := range l.models {
+		wg.Add(1)
+		go func(r *RedisModel) {
+			defer wg.Done()
+			ok, err := fn(r)
+			mu.Lock()
+			if ok {
+				n++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}(r)
+	}
+	wg.Wait()
+	return n, firstErr
+}
+
+//加锁成功需要的实例个数
+func (l *Lock) quorum() int {
+	return len(l.models)/2 + 1
+}
+
+//锁的剩余有效时间，扣除加锁耗时及时钟漂移
+func (l *Lock) validity(start time.Time) time.Duration {
+	drift := time.Duration(float64(l.ttl)*LockClockDrift) + 2*time.Millisecond
+	return l.ttl - time.Since(start) - drift
+}
+
+//生成随机令牌
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//[0,max)范围的随机时间
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(n.Int64())
+}
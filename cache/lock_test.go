@@ -0,0 +1,39 @@
+package Cache
+
+import (
+	"testing"
+	"time"
+)
+
+//有效时间过短时拒绝加锁，不连接Redis
+func TestLockRejectsShortTTL(t *testing.T) {
+	r := &RedisModel{}
+	for _, ttl := range []time.Duration{0, time.Millisecond, LockMinTTL - 1} {
+		if _, err := r.Lock("k", ttl); err != ErrLockTTLTooShort {
+			t.Errorf("%v: 应返回ErrLockTTLTooShort，实际为%v", ttl, err)
+		}
+	}
+	if _, err := r.Lock("", time.Second); err == nil {
+		t.Error("Key为空时应返回错误")
+	}
+	l := &Lock{ttl: time.Second}
+	if err := l.Refresh(time.Millisecond); err != ErrLockTTLTooShort || l.ttl != time.Second {
+		t.Errorf("续期的有效时间过短时应返回ErrLockTTLTooShort且不修改有效时间：%v %v", err, l.ttl)
+	}
+}
+
+//续期间隔为有效时间的1/3，最小1毫秒
+func TestRenewInterval(t *testing.T) {
+	cases := map[time.Duration]time.Duration{
+		3 * time.Second:      time.Second,
+		LockMinTTL:           LockMinTTL / 3,
+		2 * time.Millisecond: time.Millisecond,
+		time.Nanosecond:      time.Millisecond,
+		0:                    time.Millisecond,
+	}
+	for ttl, want := range cases {
+		if got := renewInterval(ttl); got != want {
+			t.Errorf("%v: 续期间隔应为%v，实际为%v", ttl, want, got)
+		}
+	}
+}
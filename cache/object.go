@@ -0,0 +1,91 @@
+/*
+	对象存取：SetObject/GetObject使用RedisModel的序列化方式（JSON/gob/msgpack，可压缩）保存任意数据
+	HSetStruct/HGetAllStruct将struct的字段保存为哈希表的字段，字段名使用redis标签，支持整数、浮点数、布尔、字符串及[]byte字段
+	使用方法：
+	type User struct {
+		Id   int64  `redis:"id"`
+		Name string `redis:"name"`
+	}
+	err := r.SetObject("user:1", user, 3600)
+	err := r.GetObject("user:1", &user) //不存在时返回Cache.ErrNil
+	err := r.HSetStruct("user:1", &user)
+	err := r.HGetAllStruct("user:1", &user)
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+//序列化后保存
+//@param timeout 过期时间（可选），单位：秒
+func (r *RedisModel) SetObject(key string, val interface{}, timeout ...int64) error {
+	data, err := r.codec().Marshal(val)
+	if err != nil {
+		return err
+	}
+	key = r.GenerateKey(key)
+	if len(timeout) > 0 && timeout[0] > 0 {
+		_, err = r.Do("SETEX", key, timeout[0], data)
+	} else {
+		_, err = r.Do("SET", key, data)
+	}
+	return err
+}
+
+//获取SetObject保存的数据，Key不存在时返回ErrNil
+//@param dst 接收数据的指针
+func (r *RedisModel) GetObject(key string, dst interface{}) error {
+	data, err := redis.Bytes(r.Query("GET", r.GenerateKey(key)))
+	if err != nil {
+		return err
+	}
+	return r.codec().Unmarshal(data, dst)
+}
+
+//将struct的字段保存到哈希表
+//@param s struct或struct指针
+//@param timeout 过期时间（可选），单位：秒
+func (r *RedisModel) HSetStruct(key string, s interface{}, timeout ...int64) error {
+	args := redis.Args{}.Add(r.GenerateKey(key)).AddFlat(s)
+	if len(args) < 3 {
+		return nil
+	}
+	if len(timeout) < 1 || timeout[0] <= 0 {
+		_, err := r.Do("HMSET", args...)
+		return err
+	}
+	var res *Reply
+	err := r.Pipeline(func(p *Pipe) {
+		res = p.DoRaw("HMSET", args...)
+		p.Expire(key, timeout[0])
+	})
+	if err != nil {
+		return err
+	}
+	return res.Err()
+}
+
+//获取哈希表的所有字段写入struct，Key不存在时返回ErrNil
+//@param dst struct指针
+func (r *RedisModel) HGetAllStruct(key string, dst interface{}) error {
+	values, err := redis.Values(r.Query("HGETALL", r.GenerateKey(key)))
+	if err != nil {
+		return err
+	}
+	if len(values) < 1 {
+		return ErrNil
+	}
+	return redis.ScanStruct(values, dst)
+}
+
+//序列化方式
+func (r *RedisModel) codec() Codec {
+	if r.Codec != nil {
+		return r.Codec
+	}
+	return DefaultCodec
+}
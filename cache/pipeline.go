@@ -0,0 +1,316 @@
+/*
+	Redis管道及事务，管道将多条命令一次发送到主库执行，事务使用MULTI/EXEC执行并通过WATCH实现乐观锁
+	命令中的Key自动添加Key前缀，执行结果通过命令返回的*Reply获取
+	使用方法：
+	var v *Cache.Reply
+	err := r.Pipeline(func(p *Cache.Pipe) {
+		p.Set("a", 1)
+		v = p.Incr("b")
+	})
+	n, err := v.Int64()
+	//检查并设置：余额充足时扣减，余额被其他客户端修改时自动重试
+	err := r.Tx(func(tx *Cache.Tx) error {
+		balance, err := redis.Int(tx.Query("GET", "balance"))
+		if err != nil || balance < 10 {
+			return errors.New("余额不足")
+		}
+		tx.Do("SET", "balance", balance-10)
+		return nil
+	}, "balance")
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"errors"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+const (
+	TxMaxRetries = 10 //事务WATCH的Key被修改时的最大重试次数
+)
+
+var (
+	ErrTxFailed = errors.New("事务执行失败：WATCH的Key被其他客户端修改") //超过重试次数时返回的错误
+	errNotExec  = errors.New("命令未执行")
+)
+
+type (
+	//管道，缓存待执行的命令
+	Pipe struct {
+		r    *RedisModel
+		cmds []*pipeCmd
+	}
+	pipeCmd struct {
+		name  string
+		args  []interface{}
+		reply *Reply
+	}
+
+	//事务，通过Do等方法添加的命令在MULTI/EXEC中执行，Query在WATCH的链接上立即执行
+	Tx struct {
+		*Pipe
+		conn redis.Conn
+	}
+
+	//命令执行结果，管道或事务执行完成后可用
+	Reply struct {
+		val interface{}
+		err error
+	}
+)
+
+//通过管道执行多条命令，命令一次发送到主库，返回链接错误，单条命令的错误通过Reply获取
+func (r *RedisModel) Pipeline(fn func(p *Pipe)) error {
+	p := &Pipe{r: r}
+	fn(p)
+	if len(p.cmds) < 1 {
+		return nil
+	}
+	c, err := r.getConn(r.redisWriter, r.writerSettings)
+	if err != nil {
+		p.fail(err)
+		return err
+	}
+	defer c.Close()
+	for _, cmd := range p.cmds {
+		if err = c.Send(cmd.name, cmd.args...); err != nil {
+			p.fail(err)
+			return err
+		}
+	}
+	if err = c.Flush(); err != nil {
+		p.fail(err)
+		return err
+	}
+	return p.receive(c)
+}
+
+//按顺序读取命令的执行结果，命令返回的错误写入对应的Reply
+//读取时发生链接错误则停止读取，未读取的命令均返回此错误
+func (p *Pipe) receive(c redis.Conn) error {
+	for i, cmd := range p.cmds {
+		val, err := c.Receive()
+		if e, ok := val.(redis.Error); ok {
+			val, err = nil, e
+		}
+		if _, ok := err.(redis.Error); err != nil && !ok {
+			for _, rest := range p.cmds[i:] {
+				rest.reply.val, rest.reply.err = nil, err
+			}
+			return err
+		}
+		cmd.reply.val, cmd.reply.err = val, err
+	}
+	return nil
+}
+
+//执行事务，先WATCH指定的Key再调用fn，fn中添加的命令通过MULTI/EXEC执行
+//WATCH的Key在EXEC前被修改时重新调用fn，超过TxMaxRetries次返回ErrTxFailed；fn返回错误时放弃事务并返回此错误
+//@param watchKeys 需要监视的Key
+func (r *RedisModel) Tx(fn func(tx *Tx) error, watchKeys ...string) error {
+	c, err := r.getConn(r.redisWriter, r.writerSettings)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for i := 0; i < TxMaxRetries; i++ {
+		if len(watchKeys) > 0 {
+			if _, err = c.Do("WATCH", r.generateKeys(watchKeys)...); err != nil {
+				return err
+			}
+		}
+		tx := &Tx{Pipe: &Pipe{r: r}, conn: c}
+		if err = fn(tx); err != nil {
+			c.Do("UNWATCH")
+			return err
+		}
+		if len(tx.cmds) < 1 {
+			_, err = c.Do("UNWATCH")
+			return err
+		}
+		c.Send("MULTI")
+		for _, cmd := range tx.cmds {
+			c.Send(cmd.name, cmd.args...)
+		}
+		reply, err := c.Do("EXEC")
+		if err != nil {
+			tx.fail(err)
+			return err
+		}
+		if reply == nil { //WATCH的Key被修改，事务未执行
+			continue
+		}
+		values, err := redis.Values(reply, nil)
+		if err != nil {
+			return err
+		}
+		for k, cmd := range tx.cmds {
+			if k >= len(values) {
+				break
+			}
+			cmd.reply.val, cmd.reply.err = values[k], nil
+			if e, ok := values[k].(redis.Error); ok {
+				cmd.reply.val, cmd.reply.err = nil, e
+			}
+		}
+		return nil
+	}
+	return ErrTxFailed
+}
+
+//在WATCH的链接上立即执行命令（用于事务中读取数据），第一个参数为Key（自动添加Key前缀）
+func (tx *Tx) Query(commandStr string, key string, args ...interface{}) (interface{}, error) {
+	return tx.conn.Do(commandStr, append([]interface{}{tx.r.GenerateKey(key)}, args...)...)
+}
+
+//添加命令，第一个参数为Key（自动添加Key前缀）
+func (p *Pipe) Do(commandStr string, key string, args ...interface{}) *Reply {
+	return p.add(commandStr, append([]interface{}{p.r.GenerateKey(key)}, args...))
+}
+
+//添加命令，参数不做处理（无Key或多个Key不连续的命令使用）
+func (p *Pipe) DoRaw(commandStr string, args ...interface{}) *Reply {
+	return p.add(commandStr, args)
+}
+
+//获取值
+func (p *Pipe) Get(key string) *Reply {
+	return p.Do("GET", key)
+}
+
+//设置值
+//@param timeout 过期时间（可选），单位：秒
+func (p *Pipe) Set(key string, val interface{}, timeout ...int64) *Reply {
+	if len(timeout) > 0 && timeout[0] > 0 {
+		return p.Do("SETEX", key, timeout[0], val)
+	}
+	return p.Do("SET", key, val)
+}
+
+//获取多个值
+func (p *Pipe) MGet(keys ...string) *Reply {
+	return p.add("MGET", p.r.generateKeys(keys))
+}
+
+//删除键，可删除多个
+func (p *Pipe) Del(keys ...string) *Reply {
+	return p.add("DEL", p.r.generateKeys(keys))
+}
+
+//键是否存在
+func (p *Pipe) Exists(key string) *Reply {
+	return p.Do("EXISTS", key)
+}
+
+//设置键的失效时间，单位：秒
+func (p *Pipe) Expire(key string, second int64) *Reply {
+	return p.Do("EXPIRE", key, second)
+}
+
+//值加1
+func (p *Pipe) Incr(key string) *Reply {
+	return p.Do("INCR", key)
+}
+
+//值增加n
+func (p *Pipe) IncrBy(key string, n int64) *Reply {
+	return p.Do("INCRBY", key, n)
+}
+
+//获取哈希表中的值
+func (p *Pipe) HGet(hashKey string, key string) *Reply {
+	return p.Do("HGET", hashKey, key)
+}
+
+//设置哈希表中的值
+func (p *Pipe) HSet(hashKey string, key string, val interface{}) *Reply {
+	return p.Do("HSET", hashKey, key, val)
+}
+
+//获取哈希表中的所有值
+func (p *Pipe) HGetAll(hashKey string) *Reply {
+	return p.Do("HGETALL", hashKey)
+}
+
+//哈希表中的值增加n
+func (p *Pipe) HIncrBy(hashKey string, key string, n int64) *Reply {
+	return p.Do("HINCRBY", hashKey, key, n)
+}
+
+//添加命令到队列
+func (p *Pipe) add(commandStr string, args []interface{}) *Reply {
+	reply := &Reply{err: errNotExec}
+	p.cmds = append(p.cmds, &pipeCmd{name: commandStr, args: args, reply: reply})
+	return reply
+}
+
+//执行失败时设置所有命令的错误
+func (p *Pipe) fail(err error) {
+	for _, cmd := range p.cmds {
+		cmd.reply.val, cmd.reply.err = nil, err
+	}
+}
+
+//命令数量
+func (p *Pipe) Len() int {
+	return len(p.cmds)
+}
+
+//为多个Key添加Key前缀
+func (r *RedisModel) generateKeys(keys []string) []interface{} {
+	newKeys := make([]interface{}, 0, len(keys))
+	for _, v := range keys {
+		newKeys = append(newKeys, r.GenerateKey(v))
+	}
+	return newKeys
+}
+
+//原始返回值
+func (rp *Reply) Value() (interface{}, error) {
+	return rp.val, rp.err
+}
+
+//执行错误
+func (rp *Reply) Err() error {
+	return rp.err
+}
+
+func (rp *Reply) Int() (int, error) {
+	return redis.Int(rp.val, rp.err)
+}
+
+func (rp *Reply) Int64() (int64, error) {
+	return redis.Int64(rp.val, rp.err)
+}
+
+func (rp *Reply) Float64() (float64, error) {
+	return redis.Float64(rp.val, rp.err)
+}
+
+func (rp *Reply) Bool() (bool, error) {
+	return redis.Bool(rp.val, rp.err)
+}
+
+func (rp *Reply) String() (string, error) {
+	return redis.String(rp.val, rp.err)
+}
+
+func (rp *Reply) Bytes() ([]byte, error) {
+	return redis.Bytes(rp.val, rp.err)
+}
+
+func (rp *Reply) Strings() ([]string, error) {
+	return redis.Strings(rp.val, rp.err)
+}
+
+func (rp *Reply) StringMap() (map[string]string, error) {
+	return redis.StringMap(rp.val, rp.err)
+}
+
+func (rp *Reply) Values() ([]interface{}, error) {
+	return redis.Values(rp.val, rp.err)
+}
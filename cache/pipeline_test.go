@@ -0,0 +1,87 @@
+package Cache
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+//按顺序返回预设结果的链接，记录发送的命令
+type fakeConn struct {
+	replies []fakeReply
+	sent    [][]interface{}
+	closed  bool
+}
+
+type fakeReply struct {
+	val interface{}
+	err error
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+func (c *fakeConn) Err() error { return nil }
+func (c *fakeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "" {
+		return nil, nil
+	}
+	c.Send(cmd, args...)
+	return c.Receive()
+}
+func (c *fakeConn) Send(cmd string, args ...interface{}) error {
+	c.sent = append(c.sent, append([]interface{}{cmd}, args...))
+	return nil
+}
+func (c *fakeConn) Flush() error { return nil }
+func (c *fakeConn) Receive() (interface{}, error) {
+	if len(c.replies) < 1 {
+		return nil, io.EOF
+	}
+	r := c.replies[0]
+	c.replies = c.replies[1:]
+	return r.val, r.err
+}
+
+//命令返回的错误写入对应的Reply，链接错误时停止读取并返回此错误
+func TestPipeReceive(t *testing.T) {
+	p := &Pipe{r: &RedisModel{}}
+	set := p.Set("a", 1)
+	bad := p.Incr("a")
+	get := p.Do("GET", "b")
+	rest := p.Do("GET", "c")
+	c := &fakeConn{replies: []fakeReply{
+		{"OK", nil},
+		{nil, redis.Error("ERR value is not an integer")},
+		{redis.Error("WRONGTYPE"), nil},
+	}}
+	if err := p.receive(c); err != io.EOF {
+		t.Fatalf("链接错误应返回，实际为%v", err)
+	}
+	if v, err := set.String(); v != "OK" || err != nil {
+		t.Errorf("第1条命令结果错误：%v %v", v, err)
+	}
+	if _, ok := bad.Err().(redis.Error); !ok {
+		t.Errorf("命令错误应写入Reply：%v", bad.Err())
+	}
+	if v, err := get.Value(); v != nil || err != redis.Error("WRONGTYPE") {
+		t.Errorf("命令错误应写入Reply：%v %v", v, err)
+	}
+	if rest.Err() != io.EOF {
+		t.Errorf("未读取的命令应返回链接错误：%v", rest.Err())
+	}
+
+	p = &Pipe{r: &RedisModel{}}
+	first, second := p.Incr("a"), p.Incr("b")
+	fail := errors.New("read: connection reset")
+	c = &fakeConn{replies: []fakeReply{{nil, fail}, {int64(1), nil}}}
+	if err := p.receive(c); err != fail {
+		t.Fatalf("应返回第一个链接错误，实际为%v", err)
+	}
+	if first.Err() != fail || second.Err() != fail || len(c.replies) != 1 {
+		t.Errorf("链接错误后应停止读取：%v %v %d", first.Err(), second.Err(), len(c.replies))
+	}
+}
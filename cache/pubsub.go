@@ -0,0 +1,219 @@
+/*
+	Redis发布订阅，订阅使用缓存池以外的独立链接（主库），网络错误后自动重连并重新订阅
+	收到的消息由固定数量的协程调用处理函数，PrefixChannel为true时频道名自动添加Key前缀，消息中的频道名已去掉前缀
+	使用方法：
+	n, err := r.Publish("news", "hello")
+	ctx, cancel := context.WithCancel(context.Background())
+	go r.Subscribe(ctx, func(msg *Cache.Message) {
+		fmt.Println(msg.Channel, string(msg.Data))
+	}, "news", "notice")
+	cancel() //取消订阅
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+	"github.com/misgo/aresgo/text"
+)
+
+var (
+	PubSubWorkers      = 10               //每个订阅处理消息的协程数
+	PubSubQueueSize    = 1000             //每个订阅待处理消息的队列长度，队列满时暂停接收
+	PubSubPingInterval = 30 * time.Second //订阅链接的心跳间隔，超过两个间隔未收到数据时视为链接断开
+	PubSubMaxBackoff   = 30 * time.Second //重连的最大等待时间
+)
+
+//订阅收到的消息
+type Message struct {
+	Channel string //频道名
+	Pattern string //匹配的模式（PSubscribe时）
+	Data    []byte //消息内容
+}
+
+//发布消息，返回收到消息的订阅者数量
+func (r *RedisModel) Publish(channel string, msg interface{}) (int, error) {
+	return redis.Int(r.Do("PUBLISH", r.channelName(channel), msg))
+}
+
+//订阅频道，阻塞至ctx取消，首次链接失败时返回错误，之后的网络错误自动重连
+//@param handler 消息处理函数，由多个协程并发调用
+//@param channels 频道名
+func (r *RedisModel) Subscribe(ctx context.Context, handler func(msg *Message), channels ...string) error {
+	return r.subscribe(ctx, false, handler, channels)
+}
+
+//按模式订阅频道（如"news.*"），阻塞至ctx取消，首次链接失败时返回错误，之后的网络错误自动重连
+//@param handler 消息处理函数，由多个协程并发调用
+//@param patterns 频道名模式
+func (r *RedisModel) PSubscribe(ctx context.Context, handler func(msg *Message), patterns ...string) error {
+	return r.subscribe(ctx, true, handler, patterns)
+}
+
+//订阅并分发消息
+//@param pattern 是否按模式订阅
+func (r *RedisModel) subscribe(ctx context.Context, pattern bool, handler func(msg *Message), channels []string) error {
+	if handler == nil || len(channels) < 1 {
+		return errors.New("订阅的频道及处理函数不能为空")
+	}
+	if r.writerSettings == nil {
+		return errors.New("Redis实例未初始化")
+	}
+	names := make([]interface{}, 0, len(channels))
+	for _, v := range channels {
+		names = append(names, r.channelName(v))
+	}
+	psc, err := r.subscribeConn(pattern, names)
+	if err != nil {
+		return err
+	}
+
+	msgs := make(chan *Message, PubSubQueueSize)
+	workers := PubSubWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				r.handleMessage(handler, msg)
+			}
+		}()
+	}
+
+	backoff := time.Second
+	for {
+		err = r.receive(ctx, psc, msgs)
+		psc.Close()
+		if ctx.Err() != nil {
+			break
+		}
+		Text.Log("error").Error(fmt.Sprintf("redis subscribe error:%v", err))
+		//重连并重新订阅，失败时等待时间加倍
+		for {
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if psc, err = r.subscribeConn(pattern, names); err == nil {
+				backoff = time.Second
+				break
+			}
+			Text.Log("error").Error(fmt.Sprintf("redis resubscribe error:%v", err))
+			if backoff *= 2; backoff > PubSubMaxBackoff {
+				backoff = PubSubMaxBackoff
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(msgs)
+	wg.Wait()
+	return nil
+}
+
+//创建订阅链接并发送订阅命令
+func (r *RedisModel) subscribeConn(pattern bool, names []interface{}) (redis.PubSubConn, error) {
+	c, err := r.dial(r.writerSettings, redis.DialReadTimeout(PubSubPingInterval*2))
+	if err != nil {
+		return redis.PubSubConn{}, err
+	}
+	psc := redis.PubSubConn{Conn: c}
+	if pattern {
+		err = psc.PSubscribe(names...)
+	} else {
+		err = psc.Subscribe(names...)
+	}
+	if err != nil {
+		psc.Close()
+		return redis.PubSubConn{}, err
+	}
+	return psc, nil
+}
+
+//接收消息放入队列，链接出错或ctx取消时返回
+func (r *RedisModel) receive(ctx context.Context, psc redis.PubSubConn, msgs chan<- *Message) error {
+	done := make(chan struct{})
+	defer close(done)
+	//心跳检测，ctx取消时关闭链接以结束Receive
+	go func() {
+		ticker := time.NewTicker(PubSubPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				psc.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					psc.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var msg *Message
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			msg = &Message{Channel: r.trimChannel(v.Channel), Data: v.Data}
+		case redis.PMessage:
+			msg = &Message{Channel: r.trimChannel(v.Channel), Pattern: r.trimChannel(v.Pattern), Data: v.Data}
+		case error:
+			return v
+		}
+		if msg == nil { //订阅确认及心跳回复
+			continue
+		}
+		select {
+		case msgs <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+//调用消息处理函数，捕获panic避免处理协程退出
+func (r *RedisModel) handleMessage(handler func(msg *Message), msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			Text.Log("error").Error(fmt.Sprintf("redis subscribe handler panic:%v", err))
+		}
+	}()
+	handler(msg)
+}
+
+//频道名，PrefixChannel为true时添加Key前缀
+func (r *RedisModel) channelName(channel string) string {
+	if r.PrefixChannel {
+		return r.KeyPre + channel
+	}
+	return channel
+}
+
+//去掉频道名的Key前缀
+func (r *RedisModel) trimChannel(channel string) string {
+	if r.PrefixChannel {
+		return strings.TrimPrefix(channel, r.KeyPre)
+	}
+	return channel
+}
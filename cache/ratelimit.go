@@ -0,0 +1,212 @@
+/*
+	Redis限流器，支持固定窗口、滑动窗口日志及令牌桶算法，通过Lua脚本保证原子性，多个服务实例共享配额
+	使用方法：
+	limiter := r.NewRateLimiter(Cache.RateLimitSlidingLog, 100, time.Minute) //每分钟最多100次
+	res, err := limiter.Allow("uid:1001")
+	if err == nil && !res.Allowed {
+		fmt.Println("请在", res.RetryAfter, "后重试")
+	}
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+const (
+	RateLimitFixedWindow = "fixed"   //固定窗口：窗口内计数，窗口结束后重置
+	RateLimitSlidingLog  = "sliding" //滑动窗口日志：记录每次请求的时间，统计最近一个窗口内的请求数
+	RateLimitTokenBucket = "bucket"  //令牌桶：桶容量为Limit，每个窗口匀速补充Limit个令牌，允许突发
+)
+
+var (
+	//固定窗口，返回{是否允许, 剩余次数, 重试等待毫秒数, 重置毫秒数}
+	fixedWindowScript = redis.NewScript(1, `
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl == -1 then
+	redis.call('PEXPIRE', KEYS[1], window)
+end
+local fresh = ttl == -2
+if ttl < 0 then
+	ttl = window
+end
+if cur + n > limit then
+	return {0, limit - cur, ttl, ttl}
+end
+cur = redis.call('INCRBY', KEYS[1], n)
+if fresh then
+	redis.call('PEXPIRE', KEYS[1], window)
+end
+return {1, limit - cur, 0, ttl}`)
+
+	//滑动窗口日志，有序集合中保存请求时间
+	slidingLogScript = redis.NewScript(1, `
+local limit, window, n, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local cur = redis.call('ZCARD', KEYS[1])
+if cur + n > limit then
+	local retry = window
+	local idx = cur + n - limit - 1
+	local item = redis.call('ZRANGE', KEYS[1], idx, idx, 'WITHSCORES')
+	if item[2] then
+		retry = tonumber(item[2]) + window - now
+	end
+	local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+	local reset = 0
+	if last[2] then
+		reset = tonumber(last[2]) + window - now
+	end
+	return {0, limit - cur, retry, reset}
+end
+for i = 1, n do
+	redis.call('ZADD', KEYS[1], now, ARGV[5] .. ':' .. i)
+end
+redis.call('PEXPIRE', KEYS[1], window)
+return {1, limit - cur - n, 0, window}`)
+
+	//令牌桶，哈希表中保存令牌数及最后更新时间
+	tokenBucketScript = redis.NewScript(1, `
+local limit, window, n, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local rate = limit / window
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens, ts = tonumber(data[1]), tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens, ts = limit, now
+end
+tokens = math.min(limit, tokens + math.max(0, now - ts) * rate)
+local allowed, retry = 0, 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+else
+	retry = math.ceil((n - tokens) / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, math.floor(tokens), retry, math.ceil((limit - tokens) / rate)}`)
+)
+
+type (
+	//限流器
+	RateLimiter struct {
+		Algorithm string        //算法：RateLimitFixedWindow/RateLimitSlidingLog/RateLimitTokenBucket
+		Limit     int64         //每个窗口允许的请求数（令牌桶的容量）
+		Window    time.Duration //窗口时长（令牌桶补满Limit个令牌的时间）
+		Prefix    string        //限流Key的前缀，在Key前缀之后添加
+
+		r *RedisModel
+	}
+
+	//限流结果
+	RateLimitResult struct {
+		Allowed    bool          //是否允许请求
+		Limit      int64         //每个窗口允许的请求数
+		Remaining  int64         //剩余请求数
+		Reset      time.Duration //配额完全恢复的剩余时间
+		RetryAfter time.Duration //被拒绝时需要等待的时间，允许时为0
+	}
+)
+
+//创建限流器，使用主库
+//@param algorithm 算法：RateLimitFixedWindow/RateLimitSlidingLog/RateLimitTokenBucket
+//@param limit 每个窗口允许的请求数
+//@param window 窗口时长
+func (r *RedisModel) NewRateLimiter(algorithm string, limit int64, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		Algorithm: algorithm,
+		Limit:     limit,
+		Window:    window,
+		Prefix:    "ratelimit:",
+		r:         r,
+	}
+}
+
+//请求一次
+//@param key 限流对象，如用户ID、客户端IP
+func (l *RateLimiter) Allow(key string) (*RateLimitResult, error) {
+	return l.AllowN(key, 1)
+}
+
+//请求n次（消耗n个配额），配额不足时不消耗
+//@param key 限流对象，如用户ID、客户端IP
+func (l *RateLimiter) AllowN(key string, n int64) (*RateLimitResult, error) {
+	if l.Limit < 1 || l.Window < time.Millisecond {
+		return nil, errors.New("限流器的请求数不能小于1，窗口时长不能小于1毫秒")
+	}
+	if n < 1 {
+		return nil, errors.New("请求数不能小于1")
+	}
+	args := []interface{}{l.key(key), l.Limit, l.Window.Milliseconds(), n, time.Now().UnixNano() / int64(time.Millisecond)}
+	var script *redis.Script
+	switch l.Algorithm {
+	case RateLimitFixedWindow:
+		script = fixedWindowScript
+	case RateLimitSlidingLog:
+		script = slidingLogScript
+		token, err := lockToken() //请求记录的唯一标识
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, token)
+	case RateLimitTokenBucket:
+		script = tokenBucketScript
+	default:
+		return nil, fmt.Errorf("不支持的限流算法[%s]", l.Algorithm)
+	}
+
+	c, err := l.r.getConn(l.r.redisWriter, l.r.writerSettings)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	reply, err := redis.Values(script.Do(c, args...))
+	if err != nil {
+		return nil, err
+	}
+	return parseRateLimitReply(reply, l.Limit)
+}
+
+//解析限流脚本的返回值{是否允许, 剩余次数, 重试等待毫秒数, 重置毫秒数}，负数按0处理
+func parseRateLimitReply(reply []interface{}, limit int64) (*RateLimitResult, error) {
+	if len(reply) < 4 {
+		return nil, errors.New("限流脚本返回值错误")
+	}
+	values := make([]int64, len(reply))
+	for k, v := range reply {
+		n, err := redis.Int64(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			n = 0
+		}
+		values[k] = n
+	}
+	return &RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  values[1],
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+//清除限流对象的计数
+func (l *RateLimiter) Reset(key string) error {
+	_, err := l.r.Do("DEL", l.key(key))
+	return err
+}
+
+//限流对象的Key
+func (l *RateLimiter) key(key string) string {
+	return l.r.GenerateKey(l.Prefix + l.Algorithm + ":" + key)
+}
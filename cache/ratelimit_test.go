@@ -0,0 +1,65 @@
+package Cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+//解析限流脚本的返回值，负数按0处理
+func TestParseRateLimitReply(t *testing.T) {
+	cases := []struct {
+		name  string
+		reply []interface{}
+		want  RateLimitResult
+	}{
+		{"allowed", []interface{}{int64(1), int64(9), int64(0), int64(60000)},
+			RateLimitResult{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Minute}},
+		{"rejected", []interface{}{int64(0), int64(0), int64(1500), int64(2500)},
+			RateLimitResult{Limit: 10, RetryAfter: 1500 * time.Millisecond, Reset: 2500 * time.Millisecond}},
+		{"negative", []interface{}{int64(0), int64(-3), int64(-2), int64(-2)},
+			RateLimitResult{Limit: 10}},
+		{"string", []interface{}{[]byte("1"), []byte("5"), []byte("0"), []byte("100")},
+			RateLimitResult{Allowed: true, Limit: 10, Remaining: 5, Reset: 100 * time.Millisecond}},
+	}
+	for _, c := range cases {
+		res, err := parseRateLimitReply(c.reply, 10)
+		if err != nil || *res != c.want {
+			t.Errorf("%s: 解析结果错误：%+v %v", c.name, res, err)
+		}
+	}
+	for _, reply := range [][]interface{}{
+		{int64(1), int64(1), int64(0)},
+		{int64(1), int64(1), int64(0), "x"},
+		{int64(1), int64(1), redis.Error("ERR"), int64(0)},
+	} {
+		if _, err := parseRateLimitReply(reply, 10); err == nil {
+			t.Errorf("%v: 应返回错误", reply)
+		}
+	}
+}
+
+//参数错误及不支持的算法不连接Redis直接返回错误
+func TestRateLimiterArgs(t *testing.T) {
+	r := &RedisModel{}
+	cases := []struct {
+		name string
+		l    *RateLimiter
+		n    int64
+	}{
+		{"limit", r.NewRateLimiter(RateLimitFixedWindow, 0, time.Minute), 1},
+		{"window", r.NewRateLimiter(RateLimitFixedWindow, 10, time.Microsecond), 1},
+		{"n", r.NewRateLimiter(RateLimitFixedWindow, 10, time.Minute), 0},
+		{"algorithm", r.NewRateLimiter("leaky", 10, time.Minute), 1},
+	}
+	for _, c := range cases {
+		if _, err := c.l.AllowN("k", c.n); err == nil {
+			t.Errorf("%s: 应返回错误", c.name)
+		}
+	}
+	l := r.NewRateLimiter(RateLimitSlidingLog, 10, time.Minute)
+	if got := l.key("ip:1"); got != "ratelimit:sliding:ip:1" {
+		t.Errorf("限流Key错误：%s", got)
+	}
+}
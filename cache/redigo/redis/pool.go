@@ -161,6 +161,12 @@ type Pool struct {
 	closed bool
 	active int
 
+	// aresgo本地修改（非上游代码，见cache/redigo/README.md）：
+	// 统计信息：等待连接的次数及时间、连接数达到上限（Wait为false）的次数
+	waitCount      int64
+	waitDuration   time.Duration
+	exhaustedCount int64
+
 	// Stack of idleConn with most recently used at the front.
 	// 空闲连接队列
 	idle list.List
@@ -199,6 +205,47 @@ func (p *Pool) ActiveCount() int {
 	return active
 }
 
+// aresgo本地修改（非上游代码，见cache/redigo/README.md）：IdleCount、PoolStats及Stats
+// 的实现参照上游redigo后续版本的同名API，PoolStats.ExhaustedCount为本地增加的字段。
+
+// IdleCount returns the number of idle connections in the pool.
+func (p *Pool) IdleCount() int {
+	p.mu.Lock()
+	idle := p.idle.Len()
+	p.mu.Unlock()
+	return idle
+}
+
+// PoolStats contains pool statistics.
+type PoolStats struct {
+	// ActiveCount is the number of connections in the pool. The count includes
+	// idle connections and connections in use.
+	ActiveCount int
+	// IdleCount is the number of idle connections in the pool.
+	IdleCount int
+	// WaitCount is the total number of connections waited for.
+	WaitCount int64
+	// WaitDuration is the total time blocked waiting for a new connection.
+	WaitDuration time.Duration
+	// ExhaustedCount is the total number of times Get failed with
+	// ErrPoolExhausted.
+	ExhaustedCount int64
+}
+
+// Stats returns pool's statistics.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	stats := PoolStats{
+		ActiveCount:    p.active,
+		IdleCount:      p.idle.Len(),
+		WaitCount:      p.waitCount,
+		WaitDuration:   p.waitDuration,
+		ExhaustedCount: p.exhaustedCount,
+	}
+	p.mu.Unlock()
+	return stats
+}
+
 // Close releases the resources used by the pool.
 func (p *Pool) Close() error {
 	p.mu.Lock()
@@ -229,6 +276,7 @@ func (p *Pool) release() {
 // creates a new connection.
 func (p *Pool) get() (Conn, error) {
 	p.mu.Lock()
+	waited := false
 
 	// Prune stale connections.
 
@@ -295,6 +343,7 @@ func (p *Pool) get() (Conn, error) {
 		}
 
 		if !p.Wait {
+			p.exhaustedCount++ // aresgo本地修改：统计连接数达到上限的次数
 			p.mu.Unlock()
 			return nil, ErrPoolExhausted
 		}
@@ -302,7 +351,13 @@ func (p *Pool) get() (Conn, error) {
 		if p.cond == nil {
 			p.cond = sync.NewCond(&p.mu)
 		}
+		if !waited { // aresgo本地修改：统计等待次数及时间
+			waited = true
+			p.waitCount++
+		}
+		start := nowFunc()
 		p.cond.Wait()
+		p.waitDuration += nowFunc().Sub(start)
 	}
 }
 
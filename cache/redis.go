@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/misgo/aresgo/cache/redigo/redis"
@@ -27,6 +28,15 @@ type (
 		readerSettings *RedisSettings
 		writerSettings *RedisSettings
 		KeyPre         string
+		PrefixChannel  bool  //发布订阅的频道名是否添加Key前缀
+		Codec          Codec //SetObject等方法的序列化方式，为空时使用DefaultCodec
+
+		scripts  map[string]*luaScript //已注册的Lua脚本
+		scriptMu sync.RWMutex
+
+		flight flightGroup //Remember加载数据的请求合并
+
+		cluster *redisCluster //集群模式，读写都在集群的主库执行
 	}
 	RedisSettings struct {
 		IP          string //IP地址
@@ -37,6 +47,16 @@ type (
 		MaxActive   int
 		KeyPre      string //redis key前缀
 		DbNum       int    //默认数据编号
+
+		PrefixChannel bool   //发布订阅的频道名是否添加Key前缀
+		Codec         string //序列化方式：json/gob/msgpack
+		Compress      bool   //序列化后是否使用snappy压缩
+
+		Sentinels        []string //哨兵地址（ip:port），设置后通过哨兵获取主从库地址，忽略IP及Port
+		MasterName       string   //哨兵监控的主库名称
+		SentinelPassword string   //哨兵的密码
+
+		ClusterNodes []string //集群节点地址（ip:port），设置后使用集群模式，忽略IP、Port及DbNum
 	}
 )
 
@@ -46,42 +66,51 @@ func NewRedis(settings map[string]*RedisSettings) *RedisModel {
 	r.readerSettings = settings["slave"]
 	r.writerSettings = settings["master"]
 
-	r.redisReader = r.Connect(r.readerSettings)
-	r.redisWriter = r.Connect(r.writerSettings)
+	if len(r.writerSettings.ClusterNodes) > 0 { //集群模式，每个节点使用独立的缓存池
+		r.cluster = newRedisCluster(r, r.writerSettings)
+	} else {
+		r.redisReader = r.Connect(r.readerSettings)
+		r.redisWriter = r.Connect(r.writerSettings)
+	}
 	r.KeyPre = r.writerSettings.KeyPre
+	r.PrefixChannel = r.writerSettings.PrefixChannel
+	if r.writerSettings.Codec != "" || r.writerSettings.Compress {
+		codec, err := NewCodec(r.writerSettings.Codec, r.writerSettings.Compress)
+		if err != nil {
+			Text.Log("error").Error(fmt.Sprintf("redis codec error:%s", err.Error()))
+		}
+		r.Codec = codec
+	}
 	//	fmt.Printf("%v\r\n", r.redisReader)
 	return r
 }
 
+//实例化Redis并检查主从库连接，配置缺失或连接失败时返回错误
+func OpenRedis(settings map[string]*RedisSettings) (*RedisModel, error) {
+	if settings["master"] == nil || settings["slave"] == nil {
+		return nil, errors.New("未设置Redis主从库配置")
+	}
+	r := NewRedis(settings)
+	if err := r.Ping(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
 //链接Redis
 func (r *RedisModel) Connect(settings *RedisSettings) *redis.Pool {
 	dailFunc := func() (rc redis.Conn, err error) {
-		connectStr := fmt.Sprintf("%s:%s", settings.IP, settings.Port)
-		rc, err = redis.Dial("tcp", connectStr)
-		if err != nil {
-			return nil, err
-		}
-		//如果有密码，需要进行权限认证
-		if settings.Password != "" {
-			if _, err := rc.Do("AUTH", settings.Password); err != nil {
-				rc.Close()
-				return nil, err
-			}
-		}
-		//选择默认库
-		_, selectErr := rc.Do("SELECT", settings.DbNum)
-		if selectErr != nil {
-			rc.Close()
-			return nil, selectErr
-		}
-
-		return
+		return r.dial(settings)
 	}
 
 	testFunc := func(rc redis.Conn, t time.Time) error {
 		//		if time.Since(t) < time.Minute {
 		//			return nil
 		//		}
+		if len(settings.Sentinels) > 0 { //哨兵模式检查角色，主从切换后丢弃原链接，重新链接时通过哨兵获取新地址
+			return r.checkRole(rc, settings)
+		}
 		_, err := rc.Do("PING")
 		return err
 	}
@@ -97,19 +126,83 @@ func (r *RedisModel) Connect(settings *RedisSettings) *redis.Pool {
 
 }
 
+//创建Redis链接（不使用缓存池），完成密码认证及选择默认库
+//@param options 链接选项（可选），如读写超时时间
+func (r *RedisModel) dial(settings *RedisSettings, options ...redis.DialOption) (rc redis.Conn, err error) {
+	if r.cluster != nil { //集群模式链接任意一个可用的节点
+		return r.cluster.dial(options...)
+	}
+	connectStr := fmt.Sprintf("%s:%s", settings.IP, settings.Port)
+	if len(settings.Sentinels) > 0 {
+		if connectStr, err = r.sentinelAddr(settings); err != nil {
+			return nil, err
+		}
+	}
+	return r.dialAddr(connectStr, settings, options...)
+}
+
+//创建指定地址的Redis链接，完成密码认证及选择默认库（集群模式不选择库）
+func (r *RedisModel) dialAddr(connectStr string, settings *RedisSettings, options ...redis.DialOption) (rc redis.Conn, err error) {
+	rc, err = redis.Dial("tcp", connectStr, options...)
+	if err != nil {
+		return nil, err
+	}
+	//如果有密码，需要进行权限认证
+	if settings.Password != "" {
+		if _, err := rc.Do("AUTH", settings.Password); err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	//选择默认库
+	if len(settings.ClusterNodes) < 1 {
+		if _, selectErr := rc.Do("SELECT", settings.DbNum); selectErr != nil {
+			rc.Close()
+			return nil, selectErr
+		}
+	}
+
+	return
+}
+
 //Redis链接测试
 func (r *RedisModel) Ping() error {
+	if r.cluster != nil {
+		return r.cluster.ping()
+	}
+	if r.redisReader == nil || r.redisWriter == nil {
+		return errors.New("Redis实例未初始化")
+	}
 	var err error
 	reader := r.redisReader.Get()
+	defer reader.Close()
 	err = r.redisReader.TestOnBorrow(reader, time.Now())
 	if err == nil {
 		writer := r.redisWriter.Get()
+		defer writer.Close()
 		err = r.redisWriter.TestOnBorrow(writer, time.Now())
 	}
 
 	return err
 }
 
+//关闭主从缓存池，关闭后不可再使用
+func (r *RedisModel) Close() error {
+	var err error
+	if r.cluster != nil {
+		return r.cluster.close()
+	}
+	if r.redisWriter != nil {
+		err = r.redisWriter.Close()
+	}
+	if r.redisReader != nil && r.redisReader != r.redisWriter {
+		if rerr := r.redisReader.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
 //选择库
 func (r *RedisModel) Select(num int) bool {
 	_, err := r.Do("SELECT", num)
@@ -432,6 +525,9 @@ func (r *RedisModel) GetTimeout(key string) int {
 //@param commandStr Redis命令
 //@param args 参数数组
 func (r *RedisModel) Do(commandStr string, args ...interface{}) (reply interface{}, err error) {
+	if r.cluster != nil {
+		return r.cluster.do(commandStr, args...)
+	}
 	c := r.redisWriter.Get()
 	defer c.Close()
 	if c.Err() != nil { //判断链接是否丢失，丢失后重建
@@ -449,6 +545,9 @@ func (r *RedisModel) Do(commandStr string, args ...interface{}) (reply interface
 //@param commandStr Redis命令
 //@param args 参数数组
 func (r *RedisModel) Query(commandStr string, args ...interface{}) (reply interface{}, err error) {
+	if r.cluster != nil {
+		return r.cluster.do(commandStr, args...)
+	}
 	c := r.redisReader.Get()
 	defer c.Close()
 	if c.Err() != nil { //判断链接是否丢失，丢失后重建
@@ -463,6 +562,9 @@ func (r *RedisModel) Query(commandStr string, args ...interface{}) (reply interf
 
 //获取redis链接
 func (r *RedisModel) getConn(pool *redis.Pool, settings *RedisSettings) (redis.Conn, error) {
+	if r.cluster != nil { //集群链接按Key将命令发送到对应的节点
+		return r.cluster.conn(), nil
+	}
 	c := pool.Get()
 	if c.Err() != nil { //判断链接是否丢失，丢失后重建
 		pool = r.Connect(settings)
@@ -474,16 +576,6 @@ func (r *RedisModel) getConn(pool *redis.Pool, settings *RedisSettings) (redis.C
 	return c, nil
 }
 
-//redis统计结果
-func (r *RedisModel) Stat() map[string]string {
-	var res map[string]string = make(map[string]string, 0)
-	reply, err := r.Do("INFO")
-	if err == nil {
-		fmt.Printf("%v", reply)
-	}
-	return res
-}
-
 //将数据转换为int
 func (r *RedisModel) Int(i interface{}, err error) (int, error) {
 	return redis.Int(i, err)
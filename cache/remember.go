@@ -0,0 +1,270 @@
+/*
+	缓存读取及加载（cache-aside），防止热点Key失效时大量请求同时访问数据库
+	同一进程内相同Key的加载请求合并为一次，可选使用分布式锁保证多个实例只有一个加载
+	按XFetch算法在缓存失效前以一定概率提前刷新，加载耗时越长、越接近失效时间越容易提前刷新
+	加载函数返回ErrNotFound（或nil）时可以缓存未命中的结果，避免不存在的数据反复访问数据库
+	使用方法：
+	var user User
+	err := r.Remember("user:1001", 10*time.Minute, func() (interface{}, error) {
+		u := User{}
+		if err := D("dev").Where("id = ?", 1001).Find(&u); err != nil {
+			return nil, err
+		}
+		if u.Id == 0 {
+			return nil, Cache.ErrNotFound
+		}
+		return u, nil
+	}, &user, &Cache.RememberOptions{Lock: true, Beta: 1, NegativeTTL: time.Minute, Codec: Cache.MsgpackCodec})
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+	"github.com/misgo/aresgo/text"
+)
+
+var (
+	RememberLockTTL  = 10 * time.Second //加载数据的分布式锁有效时间（持有期间自动续期）
+	RememberLockWait = 5 * time.Second  //默认等待分布式锁的时间，超时后直接加载
+
+	ErrNotFound = errors.New("数据不存在") //加载函数返回此错误表示数据不存在
+
+	errRefreshing  = errors.New("其他实例正在刷新缓存")
+	errLoaderPanic = errors.New("加载数据时发生panic")
+)
+
+const rememberHeaderLen = 17 //缓存数据头：是否存在(1字节)+失效时间(8字节，毫秒时间戳)+加载耗时(8字节，毫秒)
+
+type (
+	//Remember的选项
+	RememberOptions struct {
+		Codec       Codec         //序列化方式，默认为RedisModel的序列化方式
+		Lock        bool          //是否使用分布式锁，多个实例同时加载时只有一个访问数据库
+		LockWait    time.Duration //等待分布式锁的时间，默认为RememberLockWait
+		Beta        float64       //提前刷新系数（XFetch），大于1时更早刷新，小于等于0时不提前刷新
+		NegativeTTL time.Duration //数据不存在时缓存的时间，小于等于0时不缓存
+	}
+
+	//缓存的数据
+	rememberItem struct {
+		found    bool
+		expireAt int64 //失效时间（毫秒时间戳），0为不失效
+		delta    int64 //加载耗时（毫秒）
+		payload  []byte
+	}
+
+	//相同Key的加载请求合并
+	flightGroup struct {
+		mu    sync.Mutex
+		calls map[string]*flightCall
+	}
+	flightCall struct {
+		wg  sync.WaitGroup
+		val []byte
+		err error
+	}
+)
+
+//读取缓存写入dst，缓存不存在时调用loader加载并写入缓存；数据不存在时返回ErrNotFound
+//@param key 缓存的Key（自动添加Key前缀）
+//@param ttl 缓存时间，小于等于0时不失效
+//@param loader 加载函数，数据不存在时返回ErrNotFound或nil
+//@param dst 接收数据的指针
+//@param opts 选项（可选），不传时Beta为1，不使用分布式锁，不缓存不存在的数据
+func (r *RedisModel) Remember(key string, ttl time.Duration, loader func() (interface{}, error), dst interface{}, opts ...*RememberOptions) error {
+	opt := &RememberOptions{Beta: 1}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+	codec := opt.Codec
+	if codec == nil {
+		codec = r.codec()
+	}
+	fullKey := r.GenerateKey(key)
+	var stale *rememberItem
+	raw, err := redis.Bytes(r.Query("GET", fullKey))
+	if err == nil {
+		if item, ok := parseRememberItem(raw); ok {
+			if !item.refreshEarly(opt.Beta) {
+				return item.decode(codec, dst)
+			}
+			stale = item
+		}
+	} else if err != redis.ErrNil { //缓存不可用时直接加载
+		Text.Log("error").Error(fmt.Sprintf("redis remember get error:%s", err.Error()))
+	}
+
+	flightKey := fullKey
+	if stale != nil {
+		flightKey += "\x00refresh"
+	}
+	data, err := r.flight.do(flightKey, func() ([]byte, error) {
+		return r.loadRemember(key, ttl, loader, codec, opt, stale != nil)
+	})
+	if err != nil {
+		if stale != nil { //提前刷新失败时使用未失效的缓存
+			if err != errRefreshing {
+				Text.Log("error").Error(fmt.Sprintf("redis remember refresh error:%s", err.Error()))
+			}
+			return stale.decode(codec, dst)
+		}
+		return err
+	}
+	item, _ := parseRememberItem(data)
+	return item.decode(codec, dst)
+}
+
+//调用加载函数并写入缓存，返回缓存的数据
+//@param refresh 是否为提前刷新，提前刷新时不等待分布式锁
+func (r *RedisModel) loadRemember(key string, ttl time.Duration, loader func() (interface{}, error), codec Codec, opt *RememberOptions, refresh bool) ([]byte, error) {
+	fullKey := r.GenerateKey(key)
+	if opt.Lock {
+		var wait time.Duration
+		if !refresh {
+			if wait = opt.LockWait; wait <= 0 {
+				wait = RememberLockWait
+			}
+		}
+		lock, err := r.Lock("remember:lock:"+key, RememberLockTTL, wait)
+		if err == nil {
+			defer lock.Unlock()
+		} else if refresh {
+			return nil, errRefreshing
+		}
+		if !refresh { //其他实例可能已经加载完成
+			if raw, err := redis.Bytes(r.Do("GET", fullKey)); err == nil {
+				if _, ok := parseRememberItem(raw); ok {
+					return raw, nil
+				}
+			}
+		}
+	}
+
+	start := time.Now()
+	v, err := loader()
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	item := &rememberItem{delta: int64(time.Since(start) / time.Millisecond)}
+	expire := ttl
+	if err == ErrNotFound || isNilValue(v) {
+		if opt.NegativeTTL <= 0 {
+			return item.encode(), nil
+		}
+		expire = opt.NegativeTTL
+	} else {
+		item.found = true
+		if item.payload, err = codec.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	if expire > 0 {
+		item.expireAt = (time.Now().UnixNano() + int64(expire)) / int64(time.Millisecond)
+	}
+	data := item.encode()
+	if expire > 0 {
+		_, err = r.Do("SET", fullKey, data, "PX", int64(expire/time.Millisecond))
+	} else {
+		_, err = r.Do("SET", fullKey, data)
+	}
+	if err != nil { //写入缓存失败不影响本次结果
+		Text.Log("error").Error(fmt.Sprintf("redis remember set error:%s", err.Error()))
+	}
+	return data, nil
+}
+
+//解析缓存的数据
+func parseRememberItem(raw []byte) (*rememberItem, bool) {
+	if len(raw) < rememberHeaderLen || raw[0] > 1 {
+		return nil, false
+	}
+	return &rememberItem{
+		found:    raw[0] == 1,
+		expireAt: int64(binary.BigEndian.Uint64(raw[1:9])),
+		delta:    int64(binary.BigEndian.Uint64(raw[9:17])),
+		payload:  raw[rememberHeaderLen:],
+	}, true
+}
+
+//编码缓存的数据
+func (item *rememberItem) encode() []byte {
+	data := make([]byte, rememberHeaderLen, rememberHeaderLen+len(item.payload))
+	if item.found {
+		data[0] = 1
+	}
+	binary.BigEndian.PutUint64(data[1:9], uint64(item.expireAt))
+	binary.BigEndian.PutUint64(data[9:17], uint64(item.delta))
+	return append(data, item.payload...)
+}
+
+//将数据写入dst，数据不存在时返回ErrNotFound
+func (item *rememberItem) decode(codec Codec, dst interface{}) error {
+	if !item.found {
+		return ErrNotFound
+	}
+	return codec.Unmarshal(item.payload, dst)
+}
+
+//是否提前刷新（XFetch）：当前时间 - 加载耗时 * beta * ln(随机数) >= 失效时间
+func (item *rememberItem) refreshEarly(beta float64) bool {
+	if beta <= 0 || item.expireAt == 0 {
+		return false
+	}
+	now := float64(time.Now().UnixNano() / int64(time.Millisecond))
+	delta := float64(item.delta)
+	if delta < 1 {
+		delta = 1
+	}
+	return now-delta*beta*math.Log(1-rand.Float64()) >= float64(item.expireAt)
+}
+
+//合并相同Key的调用，只有第一个调用执行fn，其他调用等待并共享结果
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := &flightCall{err: errLoaderPanic}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err
+}
+
+//是否为nil（包括值为nil的指针），nil切片及map为空数据，不视为不存在
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package Cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//缓存数据编码后解析结果一致，格式错误的数据解析失败
+func TestRememberItemEncode(t *testing.T) {
+	item := &rememberItem{found: true, expireAt: 1760000000123, delta: 35, payload: []byte(`{"id":1}`)}
+	got, ok := parseRememberItem(item.encode())
+	if !ok || !got.found || got.expireAt != item.expireAt || got.delta != item.delta || string(got.payload) != string(item.payload) {
+		t.Fatalf("解析结果错误：%+v", got)
+	}
+	var v map[string]int
+	if err := got.decode(JSONCodec, &v); err != nil || v["id"] != 1 {
+		t.Errorf("解码结果错误：%v %v", v, err)
+	}
+	missing, ok := parseRememberItem((&rememberItem{}).encode())
+	if !ok || missing.decode(JSONCodec, &v) != ErrNotFound {
+		t.Error("不存在的数据应返回ErrNotFound")
+	}
+	for _, raw := range [][]byte{nil, make([]byte, rememberHeaderLen-1), append([]byte{2}, make([]byte, rememberHeaderLen)...)} {
+		if _, ok := parseRememberItem(raw); ok {
+			t.Errorf("格式错误的数据应解析失败：%v", raw)
+		}
+	}
+}
+
+//不失效、beta小于等于0时不提前刷新；已过失效时间时一定刷新；距失效时间很远时不刷新
+func TestRememberRefreshEarly(t *testing.T) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	cases := []struct {
+		name string
+		item rememberItem
+		beta float64
+		want bool
+	}{
+		{"no expire", rememberItem{delta: 1000}, 1, false},
+		{"beta zero", rememberItem{expireAt: now - 1, delta: 1000}, 0, false},
+		{"expired", rememberItem{expireAt: now - 1, delta: 10}, 1, true},
+		{"far", rememberItem{expireAt: now + int64(time.Hour/time.Millisecond), delta: 1}, 1, false},
+	}
+	for _, c := range cases {
+		for i := 0; i < 100; i++ {
+			if got := c.item.refreshEarly(c.beta); got != c.want {
+				t.Fatalf("%s: 结果应为%v", c.name, c.want)
+			}
+		}
+	}
+}
+
+//相同Key的并发加载只执行一次，fn发生panic时其他调用返回错误
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+	var calls int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := g.do("k", func() ([]byte, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return []byte("v"), nil
+			})
+			if err != nil || string(val) != "v" {
+				t.Errorf("结果错误：%s %v", val, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("加载应只执行一次，实际为%d次", calls)
+	}
+
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		defer func() { recover() }()
+		g.do("p", func() ([]byte, error) {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			panic("loader")
+		})
+	}()
+	<-started
+	go func() {
+		_, err := g.do("p", func() ([]byte, error) { return nil, nil })
+		done <- err
+	}()
+	if err := <-done; err != errLoaderPanic {
+		t.Errorf("加载发生panic时等待的调用应返回errLoaderPanic，实际为%v", err)
+	}
+}
+
+//nil及值为nil的指针视为不存在，空切片及map不视为不存在
+func TestIsNilValue(t *testing.T) {
+	var p *int
+	var s []int
+	var m map[string]int
+	cases := []struct {
+		v    interface{}
+		want bool
+	}{
+		{nil, true},
+		{p, true},
+		{s, false},
+		{m, false},
+		{0, false},
+		{"", false},
+	}
+	for i, c := range cases {
+		if got := isNilValue(c.v); got != c.want {
+			t.Errorf("第%d组结果错误：%v", i, got)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+/*
+	使用SCAN系列命令遍历Key及哈希表、集合、有序集合的元素，分批返回不会阻塞Redis（代替KEYS）
+	Scan的匹配规则自动添加Key前缀，回调函数中的Key不含Key前缀；集群模式依次遍历所有主库
+	遍历期间新增或删除的元素可能不返回，也可能重复返回
+	DelPattern遍历主库并分批使用UNLINK（不支持时使用DEL）删除匹配的Key
+	使用方法：
+	err := r.Scan("user:*", 100, func(key string) bool {
+		fmt.Println(key)
+		return true //返回false时停止遍历
+	})
+	err := r.HScan("user:1", "", 0, func(field string, value string) bool { return true })
+	n, err := r.DelPattern("session:*")
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+var (
+	ScanCount = 100 //每次遍历的元素个数（COUNT），调用时未指定时使用
+	DelBatch  = 500 //DelPattern每次删除的Key个数
+
+	errScanReply = errors.New("SCAN返回值错误")
+)
+
+//遍历匹配的Key（从库）
+//@param pattern 匹配规则（不含Key前缀），为空时匹配所有Key
+//@param count 每次遍历的个数，小于等于0时使用ScanCount
+//@param fn 回调函数，参数为不含Key前缀的Key，返回false时停止遍历
+func (r *RedisModel) Scan(pattern string, count int, fn func(key string) bool) error {
+	return r.scanKeys(r.redisReader, r.readerSettings, pattern, count, fn)
+}
+
+//遍历哈希表的字段
+//@param pattern 字段的匹配规则，为空时匹配所有字段
+//@param fn 回调函数，返回false时停止遍历
+func (r *RedisModel) HScan(key string, pattern string, count int, fn func(field string, value string) bool) error {
+	return r.scanKey("HSCAN", key, pattern, count, 2, func(items []string) bool {
+		return fn(items[0], items[1])
+	})
+}
+
+//遍历集合的成员
+//@param pattern 成员的匹配规则，为空时匹配所有成员
+//@param fn 回调函数，返回false时停止遍历
+func (r *RedisModel) SScan(key string, pattern string, count int, fn func(member string) bool) error {
+	return r.scanKey("SSCAN", key, pattern, count, 1, func(items []string) bool {
+		return fn(items[0])
+	})
+}
+
+//遍历有序集合的成员及分数
+//@param pattern 成员的匹配规则，为空时匹配所有成员
+//@param fn 回调函数，返回false时停止遍历
+func (r *RedisModel) ZScan(key string, pattern string, count int, fn func(member ZMember) bool) error {
+	var err error
+	scanErr := r.scanKey("ZSCAN", key, pattern, count, 2, func(items []string) bool {
+		var score float64
+		if score, err = strconv.ParseFloat(items[1], 64); err != nil {
+			return false
+		}
+		return fn(ZMember{Member: items[0], Score: score})
+	})
+	if scanErr != nil {
+		return scanErr
+	}
+	return err
+}
+
+//删除匹配的Key（主库），返回删除的个数
+//@param pattern 匹配规则（不含Key前缀），不可以为空
+func (r *RedisModel) DelPattern(pattern string) (int64, error) {
+	if pattern == "" {
+		return 0, errors.New("匹配规则不可以为空")
+	}
+	var total int64
+	var delErr error
+	cmd := "UNLINK"
+	batch := make([]interface{}, 0, DelBatch)
+	flush := func() bool {
+		if len(batch) < 1 {
+			return true
+		}
+		n, err := redis.Int64(r.Do(cmd, batch...))
+		if err != nil && cmd == "UNLINK" && strings.Contains(strings.ToLower(err.Error()), "unknown command") { //Redis 4.0以前的版本
+			cmd = "DEL"
+			n, err = redis.Int64(r.Do(cmd, batch...))
+		}
+		if err != nil {
+			delErr = err
+			return false
+		}
+		total += n
+		batch = batch[:0]
+		return true
+	}
+	err := r.scanKeys(r.redisWriter, r.writerSettings, pattern, 0, func(key string) bool {
+		batch = append(batch, r.GenerateKey(key))
+		if len(batch) >= DelBatch {
+			return flush()
+		}
+		return true
+	})
+	if err == nil && delErr == nil {
+		flush()
+	}
+	if err == nil {
+		err = delErr
+	}
+	return total, err
+}
+
+//遍历匹配的Key，同一次遍历使用同一个链接，集群模式依次遍历所有主库
+func (r *RedisModel) scanKeys(pool *redis.Pool, settings *RedisSettings, pattern string, count int, fn func(key string) bool) error {
+	if pattern == "" {
+		pattern = "*"
+	}
+	match := escapePattern(r.KeyPre) + pattern
+	each := func(c redis.Conn) (bool, error) {
+		next := true
+		err := scanCursor(c, "SCAN", nil, match, count, func(items []interface{}) bool {
+			for _, item := range items {
+				key, _ := redis.String(item, nil)
+				if next = fn(strings.TrimPrefix(key, r.KeyPre)); !next {
+					return false
+				}
+			}
+			return true
+		})
+		return next, err
+	}
+	if r.cluster != nil {
+		return r.cluster.eachMaster(each)
+	}
+	c, err := r.getConn(pool, settings)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = each(c)
+	return err
+}
+
+//遍历Key中的元素（从库），每个元素由size个值组成
+func (r *RedisModel) scanKey(commandStr string, key string, pattern string, count int, size int, fn func(items []string) bool) error {
+	if key == "" {
+		return errors.New("Key不可以为空")
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+	c, err := r.getConn(r.redisReader, r.readerSettings)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return scanCursor(c, commandStr, []interface{}{r.GenerateKey(key)}, pattern, count, func(items []interface{}) bool {
+		values, err := redis.Strings(items, nil)
+		if err != nil {
+			return false
+		}
+		for i := 0; i+size <= len(values); i += size {
+			if !fn(values[i : i+size]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+//按游标遍历直到游标为0或fn返回false
+//@param args 游标前的参数（如Key）
+func scanCursor(c redis.Conn, commandStr string, args []interface{}, pattern string, count int, fn func(items []interface{}) bool) error {
+	if count <= 0 {
+		count = ScanCount
+	}
+	cursor := "0"
+	for {
+		cmdArgs := append(append([]interface{}{}, args...), cursor, "MATCH", pattern, "COUNT", count)
+		reply, err := redis.Values(c.Do(commandStr, cmdArgs...))
+		if err != nil {
+			return err
+		}
+		if len(reply) != 2 {
+			return errScanReply
+		}
+		if cursor, err = redis.String(reply[0], nil); err != nil {
+			return err
+		}
+		items, err := redis.Values(reply[1], nil)
+		if err != nil {
+			return err
+		}
+		if !fn(items) || cursor == "0" {
+			return nil
+		}
+	}
+}
+
+//转义匹配规则中的特殊字符
+func escapePattern(s string) string {
+	var b bytes.Buffer
+	for _, ch := range s {
+		switch ch {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
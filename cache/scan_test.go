@@ -0,0 +1,19 @@
+package Cache
+
+import "testing"
+
+//转义匹配规则中的特殊字符
+func TestEscapePattern(t *testing.T) {
+	cases := map[string]string{
+		"user:1001": "user:1001",
+		"a*b?c":     `a\*b\?c`,
+		"[x]":       `\[x\]`,
+		`a\b`:       `a\\b`,
+		"中文*":       `中文\*`,
+	}
+	for s, want := range cases {
+		if got := escapePattern(s); got != want {
+			t.Errorf("%q转义后应为%q，实际为%q", s, want, got)
+		}
+	}
+}
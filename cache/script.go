@@ -0,0 +1,117 @@
+/*
+	Lua脚本管理，脚本注册时预加载到主从库，执行时使用EVALSHA，脚本不存在（NOSCRIPT）时自动使用EVAL重新加载
+	KEYS自动添加Key前缀，ARGV不做处理
+	使用方法：
+	err := r.RegisterScript("incr_max", `
+		local v = redis.call('INCR', KEYS[1])
+		if v > tonumber(ARGV[1]) then redis.call('DECR', KEYS[1]) return -1 end
+		return v`, 1)
+	n, err := redis.Int(r.RunScript("incr_max", []string{"counter"}, 100))
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"fmt"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+//已注册的脚本
+type luaScript struct {
+	*redis.Script
+	keyCount int
+}
+
+//注册Lua脚本并预加载到主从库，同名脚本会被替换
+//@param name 脚本名称
+//@param src 脚本内容
+//@param keyCount KEYS的个数，小于0时不检查执行时传入的Key个数
+func (r *RedisModel) RegisterScript(name string, src string, keyCount int) error {
+	script := &luaScript{Script: redis.NewScript(keyCount, src), keyCount: keyCount}
+	r.scriptMu.Lock()
+	if r.scripts == nil {
+		r.scripts = make(map[string]*luaScript)
+	}
+	r.scripts[name] = script
+	r.scriptMu.Unlock()
+	if err := r.loadScript(script, r.redisWriter, r.writerSettings); err != nil {
+		return err
+	}
+	if r.redisReader != r.redisWriter {
+		return r.loadScript(script, r.redisReader, r.readerSettings)
+	}
+	return nil
+}
+
+//在主库执行已注册的脚本
+//@param keys 脚本的KEYS，自动添加Key前缀
+//@param args 脚本的ARGV
+func (r *RedisModel) RunScript(name string, keys []string, args ...interface{}) (interface{}, error) {
+	script, err := r.getScript(name, keys)
+	if err != nil {
+		return nil, err
+	}
+	return r.evalScript(script, r.redisWriter, r.writerSettings, keys, args)
+}
+
+//在从库执行已注册的只读脚本
+//@param keys 脚本的KEYS，自动添加Key前缀
+//@param args 脚本的ARGV
+func (r *RedisModel) RunReadScript(name string, keys []string, args ...interface{}) (interface{}, error) {
+	script, err := r.getScript(name, keys)
+	if err != nil {
+		return nil, err
+	}
+	return r.evalScript(script, r.redisReader, r.readerSettings, keys, args)
+}
+
+//脚本是否已注册
+func (r *RedisModel) HasScript(name string) bool {
+	r.scriptMu.RLock()
+	defer r.scriptMu.RUnlock()
+	_, ok := r.scripts[name]
+	return ok
+}
+
+//获取已注册的脚本并检查Key个数
+func (r *RedisModel) getScript(name string, keys []string) (*luaScript, error) {
+	r.scriptMu.RLock()
+	script, ok := r.scripts[name]
+	r.scriptMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("脚本[%s]未注册", name)
+	}
+	if script.keyCount >= 0 && script.keyCount != len(keys) {
+		return nil, fmt.Errorf("脚本[%s]需要%d个Key，传入了%d个", name, script.keyCount, len(keys))
+	}
+	return script, nil
+}
+
+//预加载脚本
+func (r *RedisModel) loadScript(script *luaScript, pool *redis.Pool, settings *RedisSettings) error {
+	c, err := r.getConn(pool, settings)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return script.Load(c)
+}
+
+//执行脚本，KEYS添加Key前缀
+func (r *RedisModel) evalScript(script *luaScript, pool *redis.Pool, settings *RedisSettings, keys []string, args []interface{}) (interface{}, error) {
+	c, err := r.getConn(pool, settings)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var keysAndArgs []interface{}
+	if script.keyCount < 0 { //注册时未指定Key个数，需要在参数前传入
+		keysAndArgs = append(keysAndArgs, len(keys))
+	}
+	keysAndArgs = append(keysAndArgs, r.generateKeys(keys)...)
+	keysAndArgs = append(keysAndArgs, args...)
+	return script.Do(c, keysAndArgs...)
+}
@@ -0,0 +1,122 @@
+/*
+	Redis哨兵模式，设置Sentinels及MasterName后，创建链接时通过哨兵获取当前的主库（从库配置获取可用的从库，没有可用从库时使用主库）
+	从缓存池获取链接时检查ROLE，主从切换后原主库的链接被丢弃，重新创建的链接通过哨兵获取新的主库地址
+	使用方法（配置文件）：
+	"default": {
+		"master": {"sentinels": "10.0.0.1:26379;10.0.0.2:26379;10.0.0.3:26379", "master_name": "mymaster", "password": "..."},
+		"slave":  {"sentinels": "10.0.0.1:26379;10.0.0.2:26379;10.0.0.3:26379", "master_name": "mymaster", "password": "..."}
+	}
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+var (
+	SentinelTimeout = 3 * time.Second //链接哨兵及读写的超时时间
+)
+
+//通过哨兵获取主库或从库的地址，按顺序尝试所有哨兵
+func (r *RedisModel) sentinelAddr(settings *RedisSettings) (string, error) {
+	if settings.MasterName == "" {
+		return "", errors.New("哨兵模式需要设置主库名称")
+	}
+	var lastErr error
+	for _, sentinel := range settings.Sentinels {
+		c, err := redis.Dial("tcp", sentinel,
+			redis.DialConnectTimeout(SentinelTimeout),
+			redis.DialReadTimeout(SentinelTimeout),
+			redis.DialWriteTimeout(SentinelTimeout))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		addr, err := r.querySentinel(c, settings)
+		c.Close()
+		if err == nil {
+			return addr, nil
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("通过哨兵获取[%s]的地址失败：%v", settings.MasterName, lastErr)
+}
+
+//向哨兵查询地址，从库配置随机返回一个可用的从库
+func (r *RedisModel) querySentinel(c redis.Conn, settings *RedisSettings) (string, error) {
+	if settings.SentinelPassword != "" {
+		if _, err := c.Do("AUTH", settings.SentinelPassword); err != nil {
+			return "", err
+		}
+	}
+	if r.isReplica(settings) {
+		if addrs, err := sentinelReplicas(c, settings.MasterName); err == nil && len(addrs) > 0 {
+			return addrs[rand.Intn(len(addrs))], nil
+		}
+	}
+	master, err := redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", settings.MasterName))
+	if err != nil {
+		return "", err
+	}
+	if len(master) != 2 {
+		return "", fmt.Errorf("哨兵未监控主库[%s]", settings.MasterName)
+	}
+	return net.JoinHostPort(master[0], master[1]), nil
+}
+
+//查询可用的从库地址
+func sentinelReplicas(c redis.Conn, masterName string) ([]string, error) {
+	reply, err := redis.Values(c.Do("SENTINEL", "replicas", masterName))
+	if err != nil { //Redis 5以前的版本
+		if reply, err = redis.Values(c.Do("SENTINEL", "slaves", masterName)); err != nil {
+			return nil, err
+		}
+	}
+	var addrs []string
+	for _, item := range reply {
+		info, err := redis.StringMap(item, nil)
+		if err != nil {
+			continue
+		}
+		flags := info["flags"]
+		if strings.Contains(flags, "s_down") || strings.Contains(flags, "o_down") || strings.Contains(flags, "disconnected") {
+			continue
+		}
+		if status, ok := info["master-link-status"]; ok && status != "ok" {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+	}
+	return addrs, nil
+}
+
+//检查链接的角色，主库配置的链接必须为master，从库配置的链接可以为master或slave
+func (r *RedisModel) checkRole(c redis.Conn, settings *RedisSettings) error {
+	reply, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(reply) < 1 {
+		return errors.New("ROLE返回值错误")
+	}
+	role, _ := redis.String(reply[0], nil)
+	if role == "master" || role == "slave" && r.isReplica(settings) {
+		return nil
+	}
+	return fmt.Errorf("Redis链接的角色[%s]与配置不符", role)
+}
+
+//是否为从库配置（与主库配置不同）
+func (r *RedisModel) isReplica(settings *RedisSettings) bool {
+	return settings == r.readerSettings && settings != r.writerSettings
+}
@@ -0,0 +1,123 @@
+package Cache
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+//向哨兵查询地址：主库配置返回主库，从库配置返回可用的从库（replicas不支持时使用slaves），没有可用从库时返回主库
+func TestQuerySentinel(t *testing.T) {
+	master := &RedisSettings{MasterName: "mymaster"}
+	slave := &RedisSettings{MasterName: "mymaster"}
+	authed := &RedisSettings{MasterName: "mymaster", SentinelPassword: "pwd"}
+	r := &RedisModel{writerSettings: master, readerSettings: slave}
+	masterAddr := fakeReply{[]interface{}{[]byte("10.0.0.1"), []byte("6379")}, nil}
+	replicas := fakeReply{[]interface{}{
+		sentinelInfo("ip", "10.0.0.2", "port", "6379", "flags", "slave,s_down"),
+		sentinelInfo("ip", "10.0.0.3", "port", "6379", "flags", "slave,disconnected"),
+		sentinelInfo("ip", "10.0.0.4", "port", "6379", "flags", "slave", "master-link-status", "err"),
+		sentinelInfo("ip", "10.0.0.5", "port", "6380", "flags", "slave", "master-link-status", "ok"),
+		[]byte("invalid"),
+	}, nil}
+	unknown := fakeReply{nil, redis.Error("ERR Unknown sentinel subcommand 'replicas'")}
+
+	cases := []struct {
+		name     string
+		settings *RedisSettings
+		replies  []fakeReply
+		addr     string
+		sent     []string
+	}{
+		{"master", master, []fakeReply{masterAddr}, "10.0.0.1:6379", []string{"SENTINEL get-master-addr-by-name"}},
+		{"auth", authed, []fakeReply{{"OK", nil}, masterAddr}, "10.0.0.1:6379", []string{"AUTH", "SENTINEL get-master-addr-by-name"}},
+		{"replicas", slave, []fakeReply{replicas}, "10.0.0.5:6380", []string{"SENTINEL replicas"}},
+		{"slaves", slave, []fakeReply{unknown, replicas}, "10.0.0.5:6380", []string{"SENTINEL replicas", "SENTINEL slaves"}},
+		{"no replica", slave, []fakeReply{{[]interface{}{}, nil}, masterAddr}, "10.0.0.1:6379",
+			[]string{"SENTINEL replicas", "SENTINEL get-master-addr-by-name"}},
+		{"replicas error", slave, []fakeReply{unknown, unknown, masterAddr}, "10.0.0.1:6379",
+			[]string{"SENTINEL replicas", "SENTINEL slaves", "SENTINEL get-master-addr-by-name"}},
+	}
+	for _, c := range cases {
+		conn := &fakeConn{replies: c.replies}
+		addr, err := r.querySentinel(conn, c.settings)
+		if err != nil || addr != c.addr {
+			t.Errorf("%s: 地址应为%s，实际为%q %v", c.name, c.addr, addr, err)
+		}
+		var sent []string
+		for _, cmd := range conn.sent {
+			name := cmd[0].(string)
+			if name == "SENTINEL" {
+				name += " " + cmd[1].(string)
+			}
+			sent = append(sent, name)
+		}
+		if !reflect.DeepEqual(sent, c.sent) {
+			t.Errorf("%s: 发送的命令错误：%v", c.name, sent)
+		}
+	}
+
+	for name, replies := range map[string][]fakeReply{
+		"auth failed":   {{nil, redis.Error("ERR invalid password")}},
+		"not monitored": {{nil, nil}},
+		"bad reply":     {{[]interface{}{[]byte("10.0.0.1")}, nil}},
+	} {
+		settings := master
+		if name == "auth failed" {
+			settings = authed
+		}
+		if addr, err := r.querySentinel(&fakeConn{replies: replies}, settings); err == nil {
+			t.Errorf("%s: 应返回错误，实际为%q", name, addr)
+		}
+	}
+}
+
+//主库配置的链接必须为master，从库配置的链接可以为master或slave
+func TestCheckRole(t *testing.T) {
+	master := &RedisSettings{}
+	slave := &RedisSettings{}
+	r := &RedisModel{writerSettings: master, readerSettings: slave}
+	cases := []struct {
+		name     string
+		settings *RedisSettings
+		reply    fakeReply
+		ok       bool
+	}{
+		{"master on master", master, fakeReply{[]interface{}{[]byte("master"), int64(0)}, nil}, true},
+		{"slave on master", master, fakeReply{[]interface{}{[]byte("slave"), []byte("10.0.0.1")}, nil}, false},
+		{"master on slave", slave, fakeReply{[]interface{}{[]byte("master")}, nil}, true},
+		{"slave on slave", slave, fakeReply{[]interface{}{[]byte("slave")}, nil}, true},
+		{"sentinel", slave, fakeReply{[]interface{}{[]byte("sentinel")}, nil}, false},
+		{"empty", master, fakeReply{[]interface{}{}, nil}, false},
+		{"error", master, fakeReply{nil, redis.Error("ERR unknown command")}, false},
+	}
+	for _, c := range cases {
+		err := r.checkRole(&fakeConn{replies: []fakeReply{c.reply}}, c.settings)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: 检查结果错误：%v", c.name, err)
+		}
+	}
+}
+
+//主从使用相同配置时不是从库配置
+func TestIsReplica(t *testing.T) {
+	master, slave := &RedisSettings{}, &RedisSettings{}
+	r := &RedisModel{writerSettings: master, readerSettings: slave}
+	if r.isReplica(master) || !r.isReplica(slave) || r.isReplica(&RedisSettings{}) {
+		t.Error("主从配置判断错误")
+	}
+	r = &RedisModel{writerSettings: master, readerSettings: master}
+	if r.isReplica(master) {
+		t.Error("主从使用相同配置时不是从库配置")
+	}
+}
+
+//哨兵返回的从库信息（字段名、值交替排列）
+func sentinelInfo(kv ...string) []interface{} {
+	info := make([]interface{}, len(kv))
+	for i, v := range kv {
+		info[i] = []byte(v)
+	}
+	return info
+}
@@ -0,0 +1,280 @@
+/*
+	Redis运行状态统计，解析INFO命令的返回值（server、memory、clients、keyspace、replication、stats）为结构体，并统计缓存池的链接情况
+	主从模式分别获取主库及从库的信息，集群模式获取所有主库的信息
+	使用方法：
+	stats, err := r.Stat() //出错时stats中仍包含已获取到的信息
+	fmt.Println(stats.Master.Memory.UsedMemory, stats.Master.Stats.HitRate())
+	for name, pool := range r.PoolStats() {
+		fmt.Println(name, pool.ActiveCount, pool.IdleCount, pool.WaitCount)
+	}
+	不兼容的修改：Stat()原为func (r *RedisModel) Stat() map[string]string（只打印INFO，返回空map），
+	现改为返回(*RedisStats, error)，原调用方需改为接收两个返回值
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+type (
+	//Redis统计结果
+	RedisStats struct {
+		Master *RedisInfo            `json:"master,omitempty"` //主库信息（主从模式）
+		Slave  *RedisInfo            `json:"slave,omitempty"`  //从库信息（主从模式）
+		Nodes  map[string]*RedisInfo `json:"nodes,omitempty"`  //节点地址对应的信息（集群模式）
+		Pools  map[string]PoolStats  `json:"pools"`            //缓存池统计，主从模式为master/slave，集群模式为节点地址
+	}
+
+	//INFO命令的解析结果
+	RedisInfo struct {
+		Server      ServerInfo                   `json:"server"`
+		Memory      MemoryInfo                   `json:"memory"`
+		Clients     ClientsInfo                  `json:"clients"`
+		Keyspace    map[string]KeyspaceInfo      `json:"keyspace"` //数据库（如db0）对应的Key统计
+		Replication ReplicationInfo              `json:"replication"`
+		Stats       StatsInfo                    `json:"stats"`
+		Raw         map[string]map[string]string `json:"-"` //所有段落的原始数据，段落名为小写
+	}
+
+	ServerInfo struct {
+		RedisVersion    string `json:"redis_version" info:"redis_version"`
+		RedisMode       string `json:"redis_mode" info:"redis_mode"`
+		Os              string `json:"os" info:"os"`
+		ProcessId       int64  `json:"process_id" info:"process_id"`
+		TcpPort         int    `json:"tcp_port" info:"tcp_port"`
+		UptimeInSeconds int64  `json:"uptime_in_seconds" info:"uptime_in_seconds"`
+	}
+
+	MemoryInfo struct {
+		UsedMemory            int64   `json:"used_memory" info:"used_memory"` //已使用内存（字节）
+		UsedMemoryHuman       string  `json:"used_memory_human" info:"used_memory_human"`
+		UsedMemoryRss         int64   `json:"used_memory_rss" info:"used_memory_rss"`
+		UsedMemoryPeak        int64   `json:"used_memory_peak" info:"used_memory_peak"`
+		UsedMemoryPeakHuman   string  `json:"used_memory_peak_human" info:"used_memory_peak_human"`
+		MaxMemory             int64   `json:"maxmemory" info:"maxmemory"` //0为不限制
+		MaxMemoryPolicy       string  `json:"maxmemory_policy" info:"maxmemory_policy"`
+		MemFragmentationRatio float64 `json:"mem_fragmentation_ratio" info:"mem_fragmentation_ratio"`
+	}
+
+	ClientsInfo struct {
+		ConnectedClients int `json:"connected_clients" info:"connected_clients"`
+		BlockedClients   int `json:"blocked_clients" info:"blocked_clients"`
+		MaxClients       int `json:"maxclients" info:"maxclients"`
+	}
+
+	KeyspaceInfo struct {
+		Keys    int64 `json:"keys"`
+		Expires int64 `json:"expires"` //设置了过期时间的Key个数
+		AvgTTL  int64 `json:"avg_ttl"` //平均过期时间（毫秒）
+	}
+
+	ReplicationInfo struct {
+		Role                   string `json:"role" info:"role"` //master/slave
+		ConnectedSlaves        int    `json:"connected_slaves" info:"connected_slaves"`
+		MasterHost             string `json:"master_host,omitempty" info:"master_host"`
+		MasterPort             int    `json:"master_port,omitempty" info:"master_port"`
+		MasterLinkStatus       string `json:"master_link_status,omitempty" info:"master_link_status"` //up/down
+		MasterLastIoSecondsAgo int64  `json:"master_last_io_seconds_ago,omitempty" info:"master_last_io_seconds_ago"`
+		MasterSyncInProgress   int    `json:"master_sync_in_progress,omitempty" info:"master_sync_in_progress"`
+		MasterReplOffset       int64  `json:"master_repl_offset" info:"master_repl_offset"`
+	}
+
+	StatsInfo struct {
+		TotalConnectionsReceived int64   `json:"total_connections_received" info:"total_connections_received"`
+		TotalCommandsProcessed   int64   `json:"total_commands_processed" info:"total_commands_processed"`
+		InstantaneousOpsPerSec   int64   `json:"instantaneous_ops_per_sec" info:"instantaneous_ops_per_sec"`
+		InstantaneousInputKbps   float64 `json:"instantaneous_input_kbps" info:"instantaneous_input_kbps"`
+		InstantaneousOutputKbps  float64 `json:"instantaneous_output_kbps" info:"instantaneous_output_kbps"`
+		RejectedConnections      int64   `json:"rejected_connections" info:"rejected_connections"`
+		ExpiredKeys              int64   `json:"expired_keys" info:"expired_keys"`
+		EvictedKeys              int64   `json:"evicted_keys" info:"evicted_keys"`
+		KeyspaceHits             int64   `json:"keyspace_hits" info:"keyspace_hits"`
+		KeyspaceMisses           int64   `json:"keyspace_misses" info:"keyspace_misses"`
+	}
+
+	//缓存池统计
+	PoolStats struct {
+		ActiveCount    int           `json:"active_count"`    //链接总数（包括空闲链接）
+		IdleCount      int           `json:"idle_count"`      //空闲链接数
+		WaitCount      int64         `json:"wait_count"`      //等待链接的次数
+		WaitDuration   time.Duration `json:"wait_duration"`   //等待链接的总时间（纳秒）
+		ExhaustedCount int64         `json:"exhausted_count"` //链接数达到上限获取失败的次数
+	}
+)
+
+//Key命中率，没有访问时为0
+func (s StatsInfo) HitRate() float64 {
+	total := s.KeyspaceHits + s.KeyspaceMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.KeyspaceHits) / float64(total)
+}
+
+//Redis统计结果，主从模式获取主库及从库的INFO，集群模式获取所有主库的INFO
+//出错时返回已获取到的信息及第一个错误；原返回值为map[string]string，升级时需修改调用方
+func (r *RedisModel) Stat() (*RedisStats, error) {
+	stats := &RedisStats{Pools: r.PoolStats()}
+	var firstErr error
+	if r.cluster != nil {
+		stats.Nodes = make(map[string]*RedisInfo)
+		for _, addr := range r.cluster.masters() {
+			conn, err := r.cluster.nodeConn(addr)
+			if err == nil {
+				var info *RedisInfo
+				if info, err = queryInfo(conn); err == nil {
+					stats.Nodes[addr] = info
+				}
+				conn.Close()
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return stats, firstErr
+	}
+	var err error
+	if stats.Master, err = r.info(r.redisWriter, r.writerSettings); err != nil {
+		firstErr = err
+	}
+	if stats.Slave, err = r.info(r.redisReader, r.readerSettings); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return stats, firstErr
+}
+
+//缓存池统计，主从模式的Key为master/slave，集群模式的Key为节点地址
+func (r *RedisModel) PoolStats() map[string]PoolStats {
+	res := make(map[string]PoolStats)
+	if r.cluster != nil {
+		r.cluster.mu.RLock()
+		for addr, pool := range r.cluster.pools {
+			res[addr] = newPoolStats(pool)
+		}
+		r.cluster.mu.RUnlock()
+		return res
+	}
+	if r.redisWriter != nil {
+		res["master"] = newPoolStats(r.redisWriter)
+	}
+	if r.redisReader != nil {
+		res["slave"] = newPoolStats(r.redisReader)
+	}
+	return res
+}
+
+//获取缓存池对应库的INFO
+func (r *RedisModel) info(pool *redis.Pool, settings *RedisSettings) (*RedisInfo, error) {
+	if pool == nil {
+		return nil, nil
+	}
+	c, err := r.getConn(pool, settings)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return queryInfo(c)
+}
+
+//执行INFO并解析
+func queryInfo(c redis.Conn) (*RedisInfo, error) {
+	reply, err := redis.String(c.Do("INFO"))
+	if err != nil {
+		return nil, err
+	}
+	return ParseInfo(reply), nil
+}
+
+//解析INFO命令的返回值
+func ParseInfo(reply string) *RedisInfo {
+	info := &RedisInfo{Keyspace: make(map[string]KeyspaceInfo), Raw: make(map[string]map[string]string)}
+	section := ""
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			section = strings.ToLower(strings.TrimSpace(line[1:]))
+			continue
+		}
+		pos := strings.Index(line, ":")
+		if pos < 0 {
+			continue
+		}
+		if info.Raw[section] == nil {
+			info.Raw[section] = make(map[string]string)
+		}
+		info.Raw[section][line[:pos]] = line[pos+1:]
+	}
+	fillInfo(&info.Server, info.Raw["server"])
+	fillInfo(&info.Memory, info.Raw["memory"])
+	fillInfo(&info.Clients, info.Raw["clients"])
+	fillInfo(&info.Replication, info.Raw["replication"])
+	fillInfo(&info.Stats, info.Raw["stats"])
+	for db, value := range info.Raw["keyspace"] { //db0:keys=1,expires=0,avg_ttl=0
+		var ks KeyspaceInfo
+		for _, item := range strings.Split(value, ",") {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			n, _ := strconv.ParseInt(kv[1], 10, 64)
+			switch kv[0] {
+			case "keys":
+				ks.Keys = n
+			case "expires":
+				ks.Expires = n
+			case "avg_ttl":
+				ks.AvgTTL = n
+			}
+		}
+		info.Keyspace[db] = ks
+	}
+	return info
+}
+
+//按字段的info标签将段落中的值填充到结构体，无法转换的值忽略
+func fillInfo(v interface{}, values map[string]string) {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		value, ok := values[rt.Field(i).Tag.Get("info")]
+		if !ok {
+			continue
+		}
+		field := rv.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int64:
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				field.SetInt(n)
+			}
+		case reflect.Float64:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				field.SetFloat(f)
+			}
+		}
+	}
+}
+
+//转换redigo的缓存池统计
+func newPoolStats(pool *redis.Pool) PoolStats {
+	s := pool.Stats()
+	return PoolStats{
+		ActiveCount:    s.ActiveCount,
+		IdleCount:      s.IdleCount,
+		WaitCount:      s.WaitCount,
+		WaitDuration:   s.WaitDuration,
+		ExhaustedCount: s.ExhaustedCount,
+	}
+}
@@ -0,0 +1,75 @@
+package Cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+const infoReply = "# Server\r\nredis_version:6.2.6\r\nredis_mode:standalone\r\ntcp_port:6379\r\nuptime_in_seconds:abc\r\n\r\n" +
+	"# Memory\r\nused_memory:1048576\r\nused_memory_human:1.00M\r\nmaxmemory_policy:allkeys-lru\r\nmem_fragmentation_ratio:1.25\r\n\r\n" +
+	"# Clients\r\nconnected_clients:12\r\n\r\n" +
+	"# Replication\r\nrole:slave\r\nmaster_host:10.0.0.1\r\nmaster_port:6380\r\nmaster_link_status:up\r\n\r\n" +
+	"# Stats\r\nkeyspace_hits:30\r\nkeyspace_misses:10\r\ninstantaneous_input_kbps:0.52\r\n\r\n" +
+	"# Keyspace\r\ndb0:keys=100,expires=20,avg_ttl=3600\r\ndb5:keys=1,expires=0,avg_ttl=0\r\n" +
+	"# Custom Section\r\nurl:redis://a:b\r\ninvalid line\r\n"
+
+//INFO各段落解析到对应的结构体，无法转换的值忽略
+func TestParseInfo(t *testing.T) {
+	info := ParseInfo(infoReply)
+	if info.Server.RedisVersion != "6.2.6" || info.Server.TcpPort != 6379 || info.Server.UptimeInSeconds != 0 {
+		t.Errorf("server段落解析错误：%+v", info.Server)
+	}
+	if info.Memory.UsedMemory != 1048576 || info.Memory.UsedMemoryHuman != "1.00M" || info.Memory.MemFragmentationRatio != 1.25 {
+		t.Errorf("memory段落解析错误：%+v", info.Memory)
+	}
+	if info.Clients.ConnectedClients != 12 {
+		t.Errorf("clients段落解析错误：%+v", info.Clients)
+	}
+	if r := info.Replication; r.Role != "slave" || r.MasterHost != "10.0.0.1" || r.MasterPort != 6380 || r.MasterLinkStatus != "up" {
+		t.Errorf("replication段落解析错误：%+v", r)
+	}
+	if s := info.Stats; s.KeyspaceHits != 30 || s.InstantaneousInputKbps != 0.52 || s.HitRate() != 0.75 {
+		t.Errorf("stats段落解析错误：%+v", s)
+	}
+	if ks := info.Keyspace["db0"]; ks.Keys != 100 || ks.Expires != 20 || ks.AvgTTL != 3600 || len(info.Keyspace) != 2 {
+		t.Errorf("keyspace段落解析错误：%+v", info.Keyspace)
+	}
+	if info.Raw["custom section"]["url"] != "redis://a:b" {
+		t.Errorf("原始数据应保留所有段落，值中的冒号不截断：%v", info.Raw["custom section"])
+	}
+	if (StatsInfo{}).HitRate() != 0 {
+		t.Error("没有访问时命中率应为0")
+	}
+}
+
+//缓存池统计等待及连接数达到上限的次数
+func TestPoolStats(t *testing.T) {
+	dial := func() (redis.Conn, error) { return &statConn{}, nil }
+	p := &redis.Pool{Dial: dial, MaxIdle: 1, MaxActive: 1}
+	c := p.Get()
+	if c2 := p.Get(); c2.Err() != redis.ErrPoolExhausted {
+		t.Fatalf("连接数达到上限时应返回ErrPoolExhausted：%v", c2.Err())
+	}
+	p.Wait = true
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.Close()
+	}()
+	p.Get().Close()
+	s := newPoolStats(p)
+	if s.ActiveCount != 1 || s.IdleCount != 1 || s.ExhaustedCount != 1 || s.WaitCount != 1 || s.WaitDuration <= 0 {
+		t.Fatalf("缓存池统计错误：%+v", s)
+	}
+}
+
+//不连接Redis的测试链接
+type statConn struct{}
+
+func (*statConn) Close() error                                   { return nil }
+func (*statConn) Err() error                                     { return nil }
+func (*statConn) Do(string, ...interface{}) (interface{}, error) { return nil, nil }
+func (*statConn) Send(string, ...interface{}) error              { return nil }
+func (*statConn) Flush() error                                   { return nil }
+func (*statConn) Receive() (interface{}, error)                  { return nil, nil }
@@ -0,0 +1,359 @@
+/*
+	Redis数据结构操作：列表、集合、有序集合、哈希表及键操作，返回转换后的类型及错误信息
+	Key自动添加Key前缀，查询操作在从库执行，修改操作（含阻塞弹出）在主库执行
+	Key不存在或值为空时返回ErrNil
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Cache
+
+import (
+	"strings"
+	"time"
+
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+var (
+	ErrNil = redis.ErrNil //Key不存在或值为空
+)
+
+type (
+	//有序集合的成员及分数
+	ZMember struct {
+		Member string
+		Score  float64
+	}
+)
+
+//---列表---
+
+//从列表头部插入值，返回列表长度
+func (r *RedisModel) LPush(key string, vals ...interface{}) (int, error) {
+	return redis.Int(r.Do("LPUSH", r.keyArgs(key, vals...)...))
+}
+
+//从列表尾部插入值，返回列表长度
+func (r *RedisModel) RPush(key string, vals ...interface{}) (int, error) {
+	return redis.Int(r.Do("RPUSH", r.keyArgs(key, vals...)...))
+}
+
+//从列表头部弹出值
+func (r *RedisModel) LPop(key string) (string, error) {
+	return redis.String(r.Do("LPOP", r.GenerateKey(key)))
+}
+
+//从列表尾部弹出值
+func (r *RedisModel) RPop(key string) (string, error) {
+	return redis.String(r.Do("RPOP", r.GenerateKey(key)))
+}
+
+//阻塞从多个列表头部弹出值，返回弹出值的列表Key（不含Key前缀）及值，超时返回ErrNil
+//@param timeout 超时时间，单位：秒，0为一直阻塞
+func (r *RedisModel) BLPop(timeout int64, keys ...string) (string, string, error) {
+	return r.bpop("BLPOP", timeout, keys)
+}
+
+//阻塞从多个列表尾部弹出值，返回弹出值的列表Key（不含Key前缀）及值，超时返回ErrNil
+//@param timeout 超时时间，单位：秒，0为一直阻塞
+func (r *RedisModel) BRPop(timeout int64, keys ...string) (string, string, error) {
+	return r.bpop("BRPOP", timeout, keys)
+}
+
+//阻塞弹出
+func (r *RedisModel) bpop(commandStr string, timeout int64, keys []string) (string, string, error) {
+	args := append(r.generateKeys(keys), timeout)
+	vals, err := redis.Strings(r.Do(commandStr, args...))
+	if err != nil {
+		return "", "", err
+	}
+	if len(vals) != 2 {
+		return "", "", ErrNil
+	}
+	return strings.TrimPrefix(vals[0], r.KeyPre), vals[1], nil
+}
+
+//获取列表指定范围的值，stop为-1时获取到列表末尾
+func (r *RedisModel) LRange(key string, start int, stop int) ([]string, error) {
+	return redis.Strings(r.Query("LRANGE", r.GenerateKey(key), start, stop))
+}
+
+//列表长度
+func (r *RedisModel) LLen(key string) (int, error) {
+	return redis.Int(r.Query("LLEN", r.GenerateKey(key)))
+}
+
+//只保留列表指定范围的值
+func (r *RedisModel) LTrim(key string, start int, stop int) error {
+	_, err := r.Do("LTRIM", r.GenerateKey(key), start, stop)
+	return err
+}
+
+//删除列表中等于val的值，返回删除的个数
+//@param count 大于0从头部开始删除count个，小于0从尾部开始删除，0为删除全部
+func (r *RedisModel) LRem(key string, count int, val interface{}) (int, error) {
+	return redis.Int(r.Do("LREM", r.GenerateKey(key), count, val))
+}
+
+//---集合---
+
+//添加集合成员，返回新增的个数
+func (r *RedisModel) SAdd(key string, members ...interface{}) (int, error) {
+	return redis.Int(r.Do("SADD", r.keyArgs(key, members...)...))
+}
+
+//删除集合成员，返回删除的个数
+func (r *RedisModel) SRem(key string, members ...interface{}) (int, error) {
+	return redis.Int(r.Do("SREM", r.keyArgs(key, members...)...))
+}
+
+//获取集合的所有成员
+func (r *RedisModel) SMembers(key string) ([]string, error) {
+	return redis.Strings(r.Query("SMEMBERS", r.GenerateKey(key)))
+}
+
+//是否为集合成员
+func (r *RedisModel) SIsMember(key string, member interface{}) (bool, error) {
+	return redis.Bool(r.Query("SISMEMBER", r.GenerateKey(key), member))
+}
+
+//集合成员个数
+func (r *RedisModel) SCard(key string) (int, error) {
+	return redis.Int(r.Query("SCARD", r.GenerateKey(key)))
+}
+
+//多个集合的交集
+func (r *RedisModel) SInter(keys ...string) ([]string, error) {
+	return redis.Strings(r.Query("SINTER", r.generateKeys(keys)...))
+}
+
+//多个集合的并集
+func (r *RedisModel) SUnion(keys ...string) ([]string, error) {
+	return redis.Strings(r.Query("SUNION", r.generateKeys(keys)...))
+}
+
+//第一个集合与其他集合的差集
+func (r *RedisModel) SDiff(keys ...string) ([]string, error) {
+	return redis.Strings(r.Query("SDIFF", r.generateKeys(keys)...))
+}
+
+//---有序集合---
+
+//添加有序集合成员，已存在的成员更新分数，返回新增的个数
+func (r *RedisModel) ZAdd(key string, members ...ZMember) (int, error) {
+	args := []interface{}{r.GenerateKey(key)}
+	for _, m := range members {
+		args = append(args, m.Score, m.Member)
+	}
+	return redis.Int(r.Do("ZADD", args...))
+}
+
+//删除有序集合成员，返回删除的个数
+func (r *RedisModel) ZRem(key string, members ...interface{}) (int, error) {
+	return redis.Int(r.Do("ZREM", r.keyArgs(key, members...)...))
+}
+
+//成员分数增加incr，返回新的分数
+func (r *RedisModel) ZIncrBy(key string, incr float64, member interface{}) (float64, error) {
+	return redis.Float64(r.Do("ZINCRBY", r.GenerateKey(key), incr, member))
+}
+
+//成员的分数，成员不存在时返回ErrNil
+func (r *RedisModel) ZScore(key string, member interface{}) (float64, error) {
+	return redis.Float64(r.Query("ZSCORE", r.GenerateKey(key), member))
+}
+
+//成员按分数从小到大的排名（从0开始），成员不存在时返回ErrNil
+func (r *RedisModel) ZRank(key string, member interface{}) (int, error) {
+	return redis.Int(r.Query("ZRANK", r.GenerateKey(key), member))
+}
+
+//成员按分数从大到小的排名（从0开始），成员不存在时返回ErrNil
+func (r *RedisModel) ZRevRank(key string, member interface{}) (int, error) {
+	return redis.Int(r.Query("ZREVRANK", r.GenerateKey(key), member))
+}
+
+//有序集合成员个数
+func (r *RedisModel) ZCard(key string) (int, error) {
+	return redis.Int(r.Query("ZCARD", r.GenerateKey(key)))
+}
+
+//分数在[min,max]范围内的成员个数，min/max可使用"-inf"、"+inf"及"("开头的开区间
+func (r *RedisModel) ZCount(key string, min interface{}, max interface{}) (int, error) {
+	return redis.Int(r.Query("ZCOUNT", r.GenerateKey(key), min, max))
+}
+
+//按分数从小到大获取指定排名范围的成员及分数
+func (r *RedisModel) ZRange(key string, start int, stop int) ([]ZMember, error) {
+	return zMembers(r.Query("ZRANGE", r.GenerateKey(key), start, stop, "WITHSCORES"))
+}
+
+//按分数从大到小获取指定排名范围的成员及分数
+func (r *RedisModel) ZRevRange(key string, start int, stop int) ([]ZMember, error) {
+	return zMembers(r.Query("ZREVRANGE", r.GenerateKey(key), start, stop, "WITHSCORES"))
+}
+
+//按分数从小到大获取分数在[min,max]范围内的成员及分数
+//@param min 最小分数，可使用"-inf"及"("开头的开区间
+//@param max 最大分数，可使用"+inf"及"("开头的开区间
+//@param limit 分页（可选）：offset, count
+func (r *RedisModel) ZRangeByScore(key string, min interface{}, max interface{}, limit ...int) ([]ZMember, error) {
+	args := []interface{}{r.GenerateKey(key), min, max, "WITHSCORES"}
+	if len(limit) > 1 {
+		args = append(args, "LIMIT", limit[0], limit[1])
+	}
+	return zMembers(r.Query("ZRANGEBYSCORE", args...))
+}
+
+//按分数从大到小获取分数在[min,max]范围内的成员及分数
+//@param limit 分页（可选）：offset, count
+func (r *RedisModel) ZRevRangeByScore(key string, max interface{}, min interface{}, limit ...int) ([]ZMember, error) {
+	args := []interface{}{r.GenerateKey(key), max, min, "WITHSCORES"}
+	if len(limit) > 1 {
+		args = append(args, "LIMIT", limit[0], limit[1])
+	}
+	return zMembers(r.Query("ZREVRANGEBYSCORE", args...))
+}
+
+//删除分数在[min,max]范围内的成员，返回删除的个数
+func (r *RedisModel) ZRemRangeByScore(key string, min interface{}, max interface{}) (int, error) {
+	return redis.Int(r.Do("ZREMRANGEBYSCORE", r.GenerateKey(key), min, max))
+}
+
+//将WITHSCORES的返回值转换为成员列表
+func zMembers(reply interface{}, err error) ([]ZMember, error) {
+	vals, err := redis.Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, 0, len(vals)/2)
+	for i := 0; i+1 < len(vals); i += 2 {
+		score, err := redis.Float64([]byte(vals[i+1]), nil)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, ZMember{Member: vals[i], Score: score})
+	}
+	return members, nil
+}
+
+//---哈希表---
+
+//获取哈希表的所有字段及值
+func (r *RedisModel) HGetAll(key string) (map[string]string, error) {
+	return redis.StringMap(r.Query("HGETALL", r.GenerateKey(key)))
+}
+
+//获取哈希表多个字段的值，不存在的字段不在返回结果中
+func (r *RedisModel) HMGet(key string, fields ...string) (map[string]string, error) {
+	args := []interface{}{r.GenerateKey(key)}
+	for _, f := range fields {
+		args = append(args, f)
+	}
+	vals, err := redis.Values(r.Query("HMGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(fields))
+	for i, v := range vals {
+		if v == nil || i >= len(fields) {
+			continue
+		}
+		if s, err := redis.String(v, nil); err == nil {
+			res[fields[i]] = s
+		}
+	}
+	return res, nil
+}
+
+//设置哈希表多个字段的值
+func (r *RedisModel) HMSet(key string, vals map[string]interface{}) error {
+	if len(vals) < 1 {
+		return nil
+	}
+	args := []interface{}{r.GenerateKey(key)}
+	for k, v := range vals {
+		args = append(args, k, v)
+	}
+	_, err := r.Do("HMSET", args...)
+	return err
+}
+
+//哈希表字段的值增加n，返回新的值
+func (r *RedisModel) HIncrBy(key string, field string, n int64) (int64, error) {
+	return redis.Int64(r.Do("HINCRBY", r.GenerateKey(key), field, n))
+}
+
+//哈希表字段是否存在
+func (r *RedisModel) HExists(key string, field string) (bool, error) {
+	return redis.Bool(r.Query("HEXISTS", r.GenerateKey(key), field))
+}
+
+//哈希表字段个数
+func (r *RedisModel) HLen(key string) (int, error) {
+	return redis.Int(r.Query("HLEN", r.GenerateKey(key)))
+}
+
+//哈希表的所有字段
+func (r *RedisModel) HKeys(key string) ([]string, error) {
+	return redis.Strings(r.Query("HKEYS", r.GenerateKey(key)))
+}
+
+//---键---
+
+//键是否存在
+func (r *RedisModel) Exists(key string) (bool, error) {
+	return redis.Bool(r.Query("EXISTS", r.GenerateKey(key)))
+}
+
+//值加1，返回新的值
+func (r *RedisModel) Incr(key string) (int64, error) {
+	return redis.Int64(r.Do("INCR", r.GenerateKey(key)))
+}
+
+//值增加n，返回新的值
+func (r *RedisModel) IncrBy(key string, n int64) (int64, error) {
+	return redis.Int64(r.Do("INCRBY", r.GenerateKey(key), n))
+}
+
+//值减1，返回新的值
+func (r *RedisModel) Decr(key string) (int64, error) {
+	return redis.Int64(r.Do("DECR", r.GenerateKey(key)))
+}
+
+//值减少n，返回新的值
+func (r *RedisModel) DecrBy(key string, n int64) (int64, error) {
+	return redis.Int64(r.Do("DECRBY", r.GenerateKey(key), n))
+}
+
+//设置键在指定时间失效，键不存在时返回false
+func (r *RedisModel) ExpireAt(key string, t time.Time) (bool, error) {
+	return redis.Bool(r.Do("EXPIREAT", r.GenerateKey(key), t.Unix()))
+}
+
+//移除键的失效时间
+func (r *RedisModel) Persist(key string) (bool, error) {
+	return redis.Bool(r.Do("PERSIST", r.GenerateKey(key)))
+}
+
+//重命名键，新键已存在时覆盖
+func (r *RedisModel) Rename(key string, newKey string) error {
+	_, err := r.Do("RENAME", r.GenerateKey(key), r.GenerateKey(newKey))
+	return err
+}
+
+//新键不存在时重命名键，返回是否重命名成功
+func (r *RedisModel) RenameNX(key string, newKey string) (bool, error) {
+	return redis.Bool(r.Do("RENAMENX", r.GenerateKey(key), r.GenerateKey(newKey)))
+}
+
+//键的类型：string/list/set/zset/hash/stream，不存在时为none
+func (r *RedisModel) Type(key string) (string, error) {
+	return redis.String(r.Query("TYPE", r.GenerateKey(key)))
+}
+
+//Key添加前缀后与其他参数组成命令参数
+func (r *RedisModel) keyArgs(key string, args ...interface{}) []interface{} {
+	return append([]interface{}{r.GenerateKey(key)}, args...)
+}
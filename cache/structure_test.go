@@ -0,0 +1,25 @@
+package Cache
+
+import (
+	"errors"
+	"testing"
+)
+
+//WITHSCORES的返回值转换为成员列表
+func TestZMembers(t *testing.T) {
+	reply := []interface{}{[]byte("a"), []byte("1.5"), []byte("b"), []byte("-inf")}
+	members, err := zMembers(reply, nil)
+	if err != nil || len(members) != 2 || members[0].Member != "a" || members[0].Score != 1.5 || members[1].Member != "b" {
+		t.Fatalf("转换结果错误：%+v %v", members, err)
+	}
+	if _, err = zMembers([]interface{}{[]byte("a"), []byte("x")}, nil); err == nil {
+		t.Error("分数无法转换时应返回错误")
+	}
+	want := errors.New("conn error")
+	if _, err = zMembers(nil, want); err != want {
+		t.Errorf("应返回原错误，实际为%v", err)
+	}
+	if members, err = zMembers([]interface{}{}, nil); err != nil || len(members) != 0 {
+		t.Errorf("空结果应返回空列表：%+v %v", members, err)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+	聚合及便捷查询：Sum/Avg/Min/Max、Exists、Pluck、Value、Increment/Decrement、FirstOrCreate/UpdateOrCreate
+	所有方法均使用当前的查询条件（Where、表前缀、软删除条件、GroupBy/Having）
+	使用方法：
+	total, err := D("dev").Table("order").Where("uid = ?", uid).Sum("amount")
+	var ids []int64
+	err := D("dev").Table("user").Where("status = ?", 1).Pluck("id", &ids)
+	_, err := D("dev").Table("goods").Where("id = ?", id).Increment("stock", 1)
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
+)
+
+//求和，无数据时返回0
+//@param field 字段名或表达式，有分组时先按分组求和，再返回各分组值的和
+func (m *DbModel) Sum(field string) (float64, error) {
+	return m.aggregate("SUM", field)
+}
+
+//求平均值，无数据时返回0，有分组时返回各分组平均值的平均值
+func (m *DbModel) Avg(field string) (float64, error) {
+	return m.aggregate("AVG", field)
+}
+
+//求最小值，无数据时返回0
+func (m *DbModel) Min(field string) (float64, error) {
+	return m.aggregate("MIN", field)
+}
+
+//求最大值，无数据时返回0
+func (m *DbModel) Max(field string) (float64, error) {
+	return m.aggregate("MAX", field)
+}
+
+//执行聚合函数
+//@param fn 聚合函数名
+//@param field 字段名或表达式
+func (m *DbModel) aggregate(fn string, field string) (float64, error) {
+	defer m.ResetDbModel()
+	if m.dbReader == nil {
+		return 0, errors.New("数据库实例未初始化")
+	}
+	if m.TableName == "" || field == "" {
+		return 0, errors.New("数据表名及字段名不能为空")
+	}
+	sqlstr := m.buildAggregateSql(fn, field)
+	//SQL调试
+	if frame.Debug {
+		Text.Log("debug").Debug(sqlstr)
+	}
+	var val sql.NullFloat64 //无数据时为NULL
+	e := m.traceBefore(MethodSelect, sqlstr, m.Param, false)
+	err := m.dbReader.QueryRow(sqlstr, m.Param...).Scan(&val)
+	m.traceAfter(e, 1, err)
+	return val.Float64, err
+}
+
+//根据当前的查询条件构造聚合语句，有分组时先在子查询中按分组聚合，再对各分组的值聚合
+func (m *DbModel) buildAggregateSql(fn string, field string) string {
+	sql := Text.NewString("SELECT ")
+	sql.Append(fn)
+	if m.GroupByStr != "" {
+		sql.Append("(t_agg.val) AS val FROM (SELECT ")
+		sql.Append(fn)
+	}
+	sql.Append("(")
+	sql.Append(field)
+	sql.Append(") AS val FROM ")
+	sql.Append(m.TableName)
+	//where
+	if where := m.scopedWhere(); where != "" {
+		sql.Append(" WHERE ")
+		sql.Append(where)
+	}
+	//group by
+	if m.GroupByStr != "" {
+		sql.Append(" GROUP BY ")
+		sql.Append(m.GroupByStr)
+		if m.HavingStr != "" {
+			sql.Append(" HAVING ")
+			sql.Append(m.HavingStr)
+		}
+		sql.Append(") AS t_agg")
+	}
+	return sql.ToString()
+}
+
+//是否存在符合查询条件的数据
+func (m *DbModel) Exists() (bool, error) {
+	m.Column = "1"
+	m.Order = ""
+	m.Offset = 0
+	m.RowsNum = 1
+	rows, err := m.selectRows()
+	return len(rows) > 0, err
+}
+
+//查询单个字段的值列表
+//@param field 字段名
+//@param list 切片指针，元素类型支持string、bool、整数、浮点数及time.Time，NULL值为元素类型的零值
+func (m *DbModel) Pluck(field string, list interface{}) error {
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		m.ResetDbModel()
+		return errors.New("获取的数据类型必须为切片指针")
+	}
+	m.Column = field
+	rows, err := m.selectRows()
+	if err != nil {
+		return err
+	}
+	sliceType := rv.Elem().Type()
+	values := reflect.MakeSlice(sliceType, 0, len(rows))
+	for _, row := range rows {
+		item := reflect.New(sliceType.Elem()).Elem()
+		if err = setColumnValue(item, row); err != nil {
+			return err
+		}
+		values = reflect.Append(values, item)
+	}
+	rv.Elem().Set(values)
+	return nil
+}
+
+//查询第一条数据单个字段的值，没有数据时返回sql.ErrNoRows
+//@param field 字段名
+//@param dst 接收值的指针，支持string、bool、整数、浮点数及time.Time
+func (m *DbModel) Value(field string, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr {
+		m.ResetDbModel()
+		return errors.New("获取的数据类型必须为指针")
+	}
+	m.Column = field
+	m.Offset = 0
+	m.RowsNum = 1
+	rows, err := m.selectRows()
+	if err != nil {
+		return err
+	}
+	if len(rows) < 1 {
+		return sql.ErrNoRows
+	}
+	return setColumnValue(rv.Elem(), rows[0])
+}
+
+//字段值增加n（UPDATE ... SET field = field + ?），返回影响的行数
+func (m *DbModel) Increment(field string, n interface{}) (int64, error) {
+	return m.incr(field, "+", n)
+}
+
+//字段值减少n（UPDATE ... SET field = field - ?），返回影响的行数
+func (m *DbModel) Decrement(field string, n interface{}) (int64, error) {
+	return m.incr(field, "-", n)
+}
+
+//原子增减字段值
+func (m *DbModel) incr(field string, op string, n interface{}) (int64, error) {
+	if m.TableName == "" || field == "" {
+		m.ResetDbModel()
+		return -1, errors.New("数据表名及字段名不能为空")
+	}
+	sql := Text.NewString("UPDATE ")
+	sql.Append(m.TableName)
+	sql.Append(" SET ")
+	sql.Append(field)
+	sql.Append(" = ")
+	sql.Append(field)
+	sql.Append(fmt.Sprintf(" %s ?", op))
+	values := []interface{}{n}
+	if where := m.scopedWhere(); where != "" {
+		sql.Append(" WHERE ")
+		sql.Append(where)
+		values = append(values, m.Param...)
+	}
+	//sql调试
+	if frame.Debug {
+		Text.Log("debug").Debug(sql.ToString())
+	}
+	return m.Execute(MethodUpdate, sql.ToString(), values...)
+}
+
+//按查询条件查找第一条数据写入s，没有数据时将s插入数据库，返回是否为新插入的数据
+//非原子操作，并发时需要通过唯一索引保证数据唯一
+//@param s struct对象指针，插入时使用其字段值
+func (m *DbModel) FirstOrCreate(s interface{}) (bool, error) {
+	found, err := m.first(s)
+	if err != nil || found {
+		return false, err
+	}
+	_, err = m.Add(s)
+	return err == nil, err
+}
+
+//按查询条件更新数据，没有数据时将s插入数据库，返回是否为新插入的数据
+//非原子操作，并发时需要通过唯一索引保证数据唯一
+//@param s struct对象指针，插入时使用其字段值
+//@param fieldmap 数据存在时更新的字段
+func (m *DbModel) UpdateOrCreate(s interface{}, fieldmap map[string]interface{}) (bool, error) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		m.ResetDbModel()
+		return false, errors.New("数据类型必须为struct指针")
+	}
+	m.ConvertModelToMap(s)
+	st := m.saveState()
+	exists, err := m.Exists()
+	if err != nil {
+		return false, err
+	}
+	m.restoreState(st)
+	if exists {
+		_, err = m.Update(fieldmap)
+		return false, err
+	}
+	m.WhereStr = ""
+	m.Param = m.Param[:0:0]
+	_, err = m.Add(s)
+	return err == nil, err
+}
+
+//按查询条件查找第一条数据写入s，返回是否找到数据；未找到时恢复查询条件以外的状态（表名等）供插入使用
+func (m *DbModel) first(s interface{}) (bool, error) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		m.ResetDbModel()
+		return false, errors.New("数据类型必须为struct指针")
+	}
+	m.ConvertModelToMap(s)
+	st := m.saveState()
+	m.Offset = 0
+	m.RowsNum = 1
+	rows, err := m.selectRows()
+	if err != nil {
+		return false, err
+	}
+	if len(rows) < 1 {
+		m.restoreState(st)
+		m.WhereStr = ""
+		m.Param = m.Param[:0:0]
+		return false, nil
+	}
+	if err = m.ConvertMapToModel(rows[0], s); err != nil {
+		return false, err
+	}
+	return true, afterFind(s)
+}
+
+//将只有一个字段的数据行写入rv，NULL值写入零值
+func setColumnValue(rv reflect.Value, row map[string]string) error {
+	var val string
+	for _, v := range row {
+		val = v
+	}
+	if val == "NULL" {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	field := reflect.StructField{Name: "Value", Type: rv.Type(), Tag: `field:"val"`}
+	m := &DbModel{}
+	return m.convertToModelElem(rv, field, map[string]string{"val": val})
+}
@@ -0,0 +1,35 @@
+package Db
+
+import "testing"
+
+type aggSoftModel struct {
+	Id        int   `field:"id" key:"pk" auto:"1" table:"agg_soft"`
+	DeletedAt int64 `field:"deleted_at" softdelete:"1"`
+}
+
+//聚合语句包含查询条件及软删除条件，有分组时先按分组聚合再对各分组的值聚合
+func TestBuildAggregateSql(t *testing.T) {
+	aggSchema := getModelSchema(&aggSoftModel{})
+	cases := []struct {
+		name string
+		m    *DbModel
+		fn   string
+		want string
+	}{
+		{"plain", &DbModel{TableName: "order"}, "SUM", "SELECT SUM(amount) AS val FROM order"},
+		{"where", &DbModel{TableName: "order", WhereStr: "uid = ? OR vip = ?"}, "MAX",
+			"SELECT MAX(amount) AS val FROM order WHERE uid = ? OR vip = ?"},
+		{"group", &DbModel{TableName: "order", GroupByStr: "uid", HavingStr: "COUNT(1) > ?"}, "AVG",
+			"SELECT AVG(t_agg.val) AS val FROM (SELECT AVG(amount) AS val FROM order GROUP BY uid HAVING COUNT(1) > ?) AS t_agg"},
+		{"group sum", &DbModel{TableName: "order", WhereStr: "status = ?", GroupByStr: "uid"}, "SUM",
+			"SELECT SUM(t_agg.val) AS val FROM (SELECT SUM(amount) AS val FROM order WHERE status = ? GROUP BY uid) AS t_agg"},
+		{"soft delete", &DbModel{TableName: "agg_soft", WhereStr: "a = ? OR b = ?", schema: aggSchema}, "MIN",
+			"SELECT MIN(amount) AS val FROM agg_soft WHERE (a = ? OR b = ?) AND deleted_at = 0"},
+		{"unscoped", &DbModel{TableName: "agg_soft", schema: aggSchema, unscoped: true}, "SUM", "SELECT SUM(amount) AS val FROM agg_soft"},
+	}
+	for _, c := range cases {
+		if got := c.m.buildAggregateSql(c.fn, "amount"); got != c.want {
+			t.Errorf("%s: SQL错误：\n%s\n应为：\n%s", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+	查询结果缓存，缓存Find/FindList/Query/Count的结果，存储使用通用缓存（Cache.Cache）
+	缓存Key由表名、表的缓存版本号及SQL语句和参数生成，对表执行Insert/Update/Delete后更新表的缓存版本号，原有缓存自动失效
+	未设置表名的查询（如直接调用Query）无法自动失效，不缓存；需要缓存时使用Table设置关联的表
+	使用方法：
+	D("dev").SetCacheStore(Cache.NewRedisCache(aresgo.R("default")))
+	err := D("dev").Cache(60*time.Second).Where("id = ?", 1).Find(&user)
+	list, err := D("dev").Table("user").Cache(time.Minute).Query("SELECT ...", args...)
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
+)
+
+const (
+	cacheKeyPre = "dbcache:" //缓存Key前缀
+)
+
+type (
+	//查询结果缓存的存储，与通用缓存接口相同，可使用Cache.NewMemoryCache、Cache.NewRedisCache或Cache.NewLayeredCache
+	QueryCache = Cache.Cache
+)
+
+//设置查询结果缓存的存储，对当前数据库对象的所有操作生效
+func (m *DbModel) SetCacheStore(c QueryCache) *DbModel {
+	m.cacheStore = c
+	return m
+}
+
+//缓存当前查询的结果，只对当前操作生效，未设置缓存存储时不缓存
+//@param ttl 缓存时间，为0时使用frame.CacheTimeout（秒）
+//@param key 自定义缓存Key（可选），作为SQL语句及参数摘要的前缀，便于识别，不同参数的查询仍分别缓存
+func (m *DbModel) Cache(ttl time.Duration, key ...string) *DbModel {
+	if m.cacheStore == nil {
+		if frame.Debug {
+			Text.Log("debug").Debug("[db cache]未设置缓存存储，查询结果不缓存")
+		}
+		return m
+	}
+	if ttl <= 0 {
+		ttl = time.Duration(frame.CacheTimeout) * time.Second
+	}
+	m.cacheTTL = ttl
+	m.useCache = true
+	if len(key) > 0 {
+		m.cacheKey = key[0]
+	}
+	return m
+}
+
+//清除表的查询缓存（更新表的缓存版本号）
+func (m *DbModel) ClearCache(tables ...string) {
+	for _, tb := range tables {
+		m.invalidateCache(m.realTableName(tb))
+	}
+}
+
+//生成查询缓存的Key，未设置表名时无法随表数据修改失效，返回空字符串（不缓存）
+//@param suffix 同一查询条件下区分不同操作的后缀（如count）
+func (m *DbModel) queryCacheKey(sqlstr string, args []interface{}, suffix string) string {
+	if m.TableName == "" {
+		if frame.Debug {
+			Text.Log("debug").Debug("[db cache]未设置表名，查询结果不缓存")
+		}
+		return ""
+	}
+	tag := m.cacheTag(m.TableName)
+	key := sqlHash(sqlstr, args)
+	if m.cacheKey != "" {
+		key = Text.SpliceString(m.cacheKey, ":", key)
+	}
+	if suffix != "" {
+		key = Text.SpliceString(key, ":", suffix)
+	}
+	return Text.SpliceString(cacheKeyPre, m.TableName, ":", tag, ":", key)
+}
+
+//SQL语句及参数的摘要
+func sqlHash(sqlstr string, args []interface{}) string {
+	h := sha1.New()
+	h.Write([]byte(sqlstr))
+	h.Write([]byte(fmt.Sprintf("%#v", args)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+//获取表的缓存版本号，不存在时生成新的版本号
+func (m *DbModel) cacheTag(table string) string {
+	tagKey := Text.SpliceString(cacheKeyPre, "tag:", table)
+	if val, ok := m.cacheStore.Get(tagKey); ok && len(val) > 0 {
+		return string(val)
+	}
+	tag := strconv.FormatInt(time.Now().UnixNano(), 36)
+	m.cacheStore.Set(tagKey, []byte(tag), 0)
+	return tag
+}
+
+//表数据修改后更新表的缓存版本号，使原有缓存失效
+func (m *DbModel) invalidateCache(table string) {
+	if m.cacheStore == nil || table == "" {
+		return
+	}
+	tagKey := Text.SpliceString(cacheKeyPre, "tag:", table)
+	tag := strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := m.cacheStore.Set(tagKey, []byte(tag), 0); err != nil {
+		Text.Log("db_error").Error(fmt.Sprintf("invalidate cache error:%s", err.Error()))
+	}
+}
+
+//从缓存中读取查询结果，读取成功返回true
+func (m *DbModel) getCache(key string, v interface{}) bool {
+	val, ok := m.cacheStore.Get(key)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(val, v); err != nil {
+		return false
+	}
+	if frame.Debug {
+		Text.Log("debug").Debug(Text.SpliceString("[db cache]hit:", key))
+	}
+	return true
+}
+
+//将查询结果写入缓存
+func (m *DbModel) setCache(key string, v interface{}, ttl time.Duration) {
+	val, err := json.Marshal(v)
+	if err == nil {
+		err = m.cacheStore.Set(key, val, ttl)
+	}
+	if err != nil {
+		Text.Log("db_error").Error(fmt.Sprintf("set cache error:%s", err.Error()))
+	}
+}
@@ -0,0 +1,57 @@
+package Db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+//自定义Key时不同参数的查询分别缓存，相同查询命中缓存
+func TestQueryCacheCustomKey(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	db.SetCacheStore(Cache.NewMemoryCache(Cache.DefaultMaxEntries))
+	for _, id := range []int{1, 1, 2, 2} {
+		if _, err := db.Table("user").Cache(time.Minute, "user_info").Where("id = ?", id).Select(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(mock.Records()); n != 2 {
+		t.Fatalf("不同参数应分别查询，相同参数命中缓存，实际执行%d次", n)
+	}
+}
+
+//未设置表名的查询无法失效，不缓存
+func TestQueryCacheWithoutTable(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	db.SetCacheStore(Cache.NewMemoryCache(Cache.DefaultMaxEntries))
+	for i := 0; i < 2; i++ {
+		if _, err := db.Cache(time.Minute).Query("SELECT * FROM user WHERE id = ?", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(mock.Records()); n != 2 {
+		t.Fatalf("未设置表名时不应缓存，实际执行%d次", n)
+	}
+}
+
+//修改表数据后原有缓存失效
+func TestQueryCacheInvalidate(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	db.SetCacheStore(Cache.NewMemoryCache(Cache.DefaultMaxEntries))
+	query := func() {
+		if _, err := db.Table("user").Cache(time.Minute).Where("id = ?", 1).Select(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query()
+	query()
+	if _, err := db.Table("user").Where("id = ?", 1).Update(map[string]interface{}{"name": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	query()
+	if n := len(mock.Records()); n != 3 {
+		t.Fatalf("修改后应重新查询，实际执行%d次", n)
+	}
+}
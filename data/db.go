@@ -53,6 +53,15 @@ type (
 		fieldStructMap  map[string]string
 		EnableTbPre     bool
 		TbPre           string
+		charset         string        //数据库字符集，同步表结构时使用
+		schema          *modelSchema  //当前操作的struct模型结构
+		unscoped        bool          //是否取消软删除过滤
+		hooks           []QueryHook   //SQL执行钩子
+		counter         *QueryCounter //SQL执行次数统计，只对当前操作生效
+		cacheStore      QueryCache    //查询结果缓存的存储
+		useCache        bool          //是否缓存当前查询的结果
+		cacheTTL        time.Duration //缓存时间
+		cacheKey        string        //自定义缓存Key
 	}
 
 	DbSettings struct {
@@ -73,22 +82,17 @@ func NewDb(driver string, config map[string]*DbSettings) *DbModel {
 	db.ResetDbModel()
 	//拼装SQL语句
 	if dbWriterConfig, ok := config["master"]; ok {
-		dbWriterConfigStr := Text.SpliceString(dbWriterConfig.User, ":", dbWriterConfig.Password,
-			"@tcp(", dbWriterConfig.Ip, ":", dbWriterConfig.Port, ")/", dbWriterConfig.DefaultDb,
-			"?charset=", dbWriterConfig.Charset)
-		db.dbWriter = Init(driver, dbWriterConfigStr)
+		db.dbWriter = Init(driver, dbWriterConfig.dsn())
 		db.EnableTbPre = dbWriterConfig.EnableTbPre
 		db.TbPre = dbWriterConfig.TbPre
+		db.charset = dbWriterConfig.Charset
 		//		fmt.Println(dbWriterConfigStr)
 	} else {
 		errMsg := fmt.Sprintf("无法连接到主数据库[Ip:%s;port:%s]", dbWriterConfig.Ip, dbWriterConfig.Port)
 		panic(errMsg)
 	}
 	if dbReaderConfig, ok := config["slave"]; ok {
-		dbReaderConfigStr := Text.SpliceString(dbReaderConfig.User, ":", dbReaderConfig.Password,
-			"@tcp(", dbReaderConfig.Ip, ":", dbReaderConfig.Port, ")/", dbReaderConfig.DefaultDb,
-			"?charset=", dbReaderConfig.Charset)
-		db.dbReader = Init(driver, dbReaderConfigStr)
+		db.dbReader = Init(driver, dbReaderConfig.dsn())
 		//		fmt.Println(dbReaderConfigStr)
 	} else {
 		errMsg := fmt.Sprintf("无法连接到从数据库[Ip:%s;port:%s]", dbReaderConfig.Ip, dbReaderConfig.Port)
@@ -98,25 +102,83 @@ func NewDb(driver string, config map[string]*DbSettings) *DbModel {
 	return db
 }
 
-//初始化数据库
+//创建数据库对象并检查主从库连接，配置缺失或连接失败时返回错误
+func OpenDb(driver string, config map[string]*DbSettings) (*DbModel, error) {
+	writerConfig, ok := config["master"]
+	if !ok || writerConfig == nil {
+		return nil, errors.New("未设置主数据库配置")
+	}
+	readerConfig, ok := config["slave"]
+	if !ok || readerConfig == nil {
+		return nil, errors.New("未设置从数据库配置")
+	}
+	writer, err := Open(driver, writerConfig.dsn())
+	if err != nil {
+		return nil, fmt.Errorf("无法连接到主数据库[Ip:%s;port:%s]:%s", writerConfig.Ip, writerConfig.Port, err.Error())
+	}
+	reader, err := Open(driver, readerConfig.dsn())
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("无法连接到从数据库[Ip:%s;port:%s]:%s", readerConfig.Ip, readerConfig.Port, err.Error())
+	}
+	return NewDbFromConn(reader, writer, writerConfig), nil
+}
+
+//数据库连接字符串
+func (c *DbSettings) dsn() string {
+	return Text.SpliceString(c.User, ":", c.Password,
+		"@tcp(", c.Ip, ":", c.Port, ")/", c.DefaultDb,
+		"?charset=", c.Charset)
+}
+
+//使用已创建的*sql.DB创建数据库对象，可用于接入自定义的连接或驱动（如单元测试使用的dbtest）
+//@param reader 从库连接
+//@param writer 主库连接
+//@param config 表前缀及字符集配置（可选）
+func NewDbFromConn(reader *sql.DB, writer *sql.DB, config ...*DbSettings) *DbModel {
+	db := &DbModel{}
+	db.ResetDbModel()
+	db.dbReader = reader
+	db.dbWriter = writer
+	if len(config) > 0 && config[0] != nil {
+		db.EnableTbPre = config[0].EnableTbPre
+		db.TbPre = config[0].TbPre
+		db.charset = config[0].Charset
+	}
+	return db
+}
+
+//初始化数据库，连接失败时返回nil
 func Init(driver string, linkstr string) *sql.DB {
-	defer func() { //捕捉panic错误避免崩溃
-		if r := recover(); r != nil {
-			fmt.Printf("[ERROR]发生错误：数据库[%s]服务链接失败！请检查数据库！\r\n", driver)
-			Text.Log("db_error").Error(fmt.Sprintf("db init ping error:%s", r))
-		}
-	}()
+	db, err := Open(driver, linkstr)
+	if err != nil {
+		fmt.Printf("[ERROR]发生错误：数据库[%s]服务链接失败！请检查数据库！\r\n", driver)
+		Text.Log("db_error").Error(fmt.Sprintf("db init ping error:%s", err.Error()))
+		return nil
+	}
+	return db
+}
+
+//打开数据库连接并检查连接是否可用
+func Open(driver string, linkstr string) (*sql.DB, error) {
 	db, err := sql.Open(driver, linkstr)
-	checkErr(err)
+	if err != nil {
+		return nil, err
+	}
 	db.SetMaxOpenConns(2000) //设置最大打开的连接数，默认值为0表示不限制,可以避免并发太高导致连接mysql出现too many connections的错误
 	db.SetMaxIdleConns(1000) //设置闲置的连接数,当开启的一个连接使用完成后可以放在池里等候下一次使用
-	err = db.Ping()
-	checkErr(err)
-	return db
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 //数据库连接判断，连接未中断返回nil
 func (m *DbModel) Ping() error {
+	if m.dbReader == nil || m.dbWriter == nil {
+		return errors.New("数据库实例未初始化")
+	}
 	err := m.dbReader.Ping()
 	if err == nil {
 		err = m.dbWriter.Ping()
@@ -124,14 +186,23 @@ func (m *DbModel) Ping() error {
 	return err
 }
 
-//设定数据表名
-func (m *DbModel) Table(tbname string) *DbModel {
-	if m.EnableTbPre {
-		m.TableName = fmt.Sprintf("%s%s", m.TbPre, tbname)
-	} else {
-		m.TableName = tbname
+//关闭主从库连接池，关闭后不可再使用
+func (m *DbModel) Close() error {
+	var err error
+	if m.dbWriter != nil {
+		err = m.dbWriter.Close()
+	}
+	if m.dbReader != nil && m.dbReader != m.dbWriter {
+		if rerr := m.dbReader.Close(); err == nil {
+			err = rerr
+		}
 	}
+	return err
+}
 
+//设定数据表名
+func (m *DbModel) Table(tbname string) *DbModel {
+	m.TableName = m.realTableName(tbname)
 	return m
 }
 
@@ -207,6 +278,9 @@ func (m *DbModel) FindList(structList interface{}) error {
 			if err != nil {
 				return err
 			}
+			if err = afterFind(rvNew.Interface()); err != nil {
+				return err
+			}
 			rv.Set(reflect.Append(rv, reflect.Indirect(reflect.ValueOf(rvNew.Interface()))))
 		}
 		return nil
@@ -242,7 +316,7 @@ func (m *DbModel) Find(i interface{}) error {
 			}
 			break
 		}
-		return nil
+		return afterFind(i)
 	} else {
 		if frame.Debug {
 			Text.Log("debug").Debug("未能查找到数据")
@@ -305,7 +379,7 @@ func (m *DbModel) FindByPK(i interface{}, pkArgs ...interface{}) error {
 			}
 			break
 		}
-		return nil
+		return afterFind(i)
 	} else {
 		if frame.Debug {
 			Text.Log("debug").Debug("未能查找到数据")
@@ -315,19 +389,54 @@ func (m *DbModel) FindByPK(i interface{}, pkArgs ...interface{}) error {
 
 }
 
-//用户CURD操作时,查询记录总数
+//用户CURD操作时,查询记录总数（设置了GroupBy时统计分组后的记录数）
 func (m *DbModel) Count() int {
-	defer func() { //捕捉panic错误避免崩溃
-		if r := recover(); r != nil {
-			Text.Log("db_error").Error(fmt.Sprintf("count error:%s", r))
+	count, err := m.count()
+	if err != nil {
+		Text.Log("db").Error(fmt.Sprintf("%v", err.Error()))
+	}
+	return count
+}
+
+//查询记录总数并返回错误信息
+func (m *DbModel) count() (int, error) {
+	defer m.ResetDbModel()
+	if m.dbReader == nil {
+		return 0, errors.New("数据库实例未初始化")
+	}
+	sql := m.buildCountSql()
+	//SQL调试
+	if frame.Debug {
+		Text.Log("debug").Debug(sql)
+	}
+	var count int = 0
+	var cacheKey string
+	if m.useCache {
+		cacheKey = m.queryCacheKey(sql, m.Param, "count")
+		if cacheKey != "" && m.getCache(cacheKey, &count) {
+			return count, nil
 		}
-	}()
+	}
+	e := m.traceBefore(MethodSelect, sql, m.Param, false)
+	err := m.dbReader.QueryRow(sql, m.Param...).Scan(&count)
+	m.traceAfter(e, 1, err)
+	if err == nil && cacheKey != "" {
+		m.setCache(cacheKey, count, m.cacheTTL)
+	}
+	return count, err
+}
+
+//根据当前的查询条件构造统计总数的语句，有分组时通过子查询统计分组数
+func (m *DbModel) buildCountSql() string {
 	sql := Text.NewString("SELECT COUNT(1) AS total FROM ")
+	if m.GroupByStr != "" {
+		sql.Append("(SELECT 1 FROM ")
+	}
 	sql.Append(m.TableName)
 	//where
-	if m.WhereStr != "" {
+	if where := m.scopedWhere(); where != "" {
 		sql.Append(" WHERE ")
-		sql.Append(m.WhereStr)
+		sql.Append(where)
 	}
 	//group by
 	if m.GroupByStr != "" {
@@ -337,29 +446,26 @@ func (m *DbModel) Count() int {
 			sql.Append(" HAVING ")
 			sql.Append(m.HavingStr)
 		}
+		sql.Append(") AS t_count")
 	}
+	return sql.ToString()
+}
+
+//用户CURD操作时,根据struct结构体查询出结果
+func (m *DbModel) Select() (*[]map[string]string, error) {
+	sql := m.buildSelectSql()
 	//SQL调试
 	if frame.Debug {
-		Text.Log("debug").Debug(sql.ToString())
+		Text.Log("debug").Debug(sql)
 	}
 
-	res, err := m.GetRow(sql.ToString(), m.Param...)
-	var count int = 0
-	if err == nil {
-		if len(res) > 0 {
-			total, reserr := strconv.Atoi(res["total"])
-			if reserr == nil {
-				count = total
-			}
-		}
-	} else {
-		Text.Log("db").Error(fmt.Sprintf("%v", err.Error()))
-	}
-	return count
+	//	ret := make([]map[string]string, 0)
+	//	return &ret, errors.New("debug")
+	return m.Query(sql, m.Param...)
 }
 
-//用户CURD操作时,根据struct结构体查询出结果
-func (m *DbModel) Select() (*[]map[string]string, error) {
+//根据当前的查询条件构造SELECT语句
+func (m *DbModel) buildSelectSql() string {
 	sql := Text.NewString("SELECT ")
 	//column
 	if m.Column != "" {
@@ -371,9 +477,9 @@ func (m *DbModel) Select() (*[]map[string]string, error) {
 	sql.Append(" FROM ")
 	sql.Append(m.TableName)
 	//where
-	if m.WhereStr != "" {
+	if where := m.scopedWhere(); where != "" {
 		sql.Append(" WHERE ")
-		sql.Append(m.WhereStr)
+		sql.Append(where)
 	}
 	//group by
 	if m.GroupByStr != "" {
@@ -397,14 +503,7 @@ func (m *DbModel) Select() (*[]map[string]string, error) {
 		sql.Append(",")
 		sql.Append(strconv.Itoa(m.RowsNum))
 	}
-	//SQL调试
-	if frame.Debug {
-		Text.Log("debug").Debug(sql.ToString())
-	}
-
-	//	ret := make([]map[string]string, 0)
-	//	return &ret, errors.New("debug")
-	return m.Query(sql.ToString(), m.Param...)
+	return sql.ToString()
 }
 
 //将数据库查询出的数据映射到struct
@@ -545,6 +644,7 @@ func (m *DbModel) convertToModelElem(fieldValue reflect.Value, field reflect.Str
 //将struct对象转换为Map，获取Map中自定义标签属性
 //field:数据库中字段名；key:主键是PK，其他是field，如果为notfield代表着个字段不是数据库字段值,auto代表此字段是数据库字段值但是属于系统生成的；table表名，取第一个定义的table
 func (m *DbModel) ConvertModelToMap(s interface{}) *DbModel {
+	m.schema = getModelSchema(s)
 	if reflect.TypeOf(reflect.Indirect(reflect.ValueOf(s)).Interface()).Kind() == reflect.Slice {
 		sliceValue := reflect.Indirect(reflect.ValueOf(s))
 		sliceElementType := sliceValue.Type().Elem()
@@ -605,26 +705,35 @@ func (m *DbModel) setFieldMap(field reflect.StructField, rv reflect.Value) {
 
 	//如果设置table标签用则采用table的值，如果已经执行过Table方法了，此标签失效
 	if tagTable != "" && m.TableName == "" {
-		if m.EnableTbPre {
-			m.TableName = fmt.Sprintf("%s%s", m.TbPre, tagTable)
-		} else {
-			m.TableName = tagTable
-		}
+		m.TableName = m.realTableName(tagTable)
 	}
 }
 
 //添加新的对象到数据库，可以将struct保存到数据库，字段不一致的通过struct tag来解决
 func (m *DbModel) Add(s interface{}) (int64, error) {
+	if err := beforeInsert(s); err != nil {
+		m.ResetDbModel()
+		return -1, err
+	}
 	m.ConvertModelToMap(s)
 
 	if len(m.FieldMap) > 0 {
-		return m.Insert(m.FieldMap)
+		id, err := m.Insert(m.FieldMap)
+		if err == nil {
+			setAutoIncrement(s, id) //自增主键回写
+			err = afterInsert(s)
+		}
+		return id, err
 	}
 	return -1, nil
 }
 
 //保存对象到数据库
 func (m *DbModel) Save(s interface{}) (int64, error) {
+	if err := beforeUpdate(s); err != nil {
+		m.ResetDbModel()
+		return -1, err
+	}
 	m.ConvertModelToMap(s)
 	//有数据更新才调用更新方法
 	if len(m.FieldMap) > 0 {
@@ -642,11 +751,61 @@ func (m *DbModel) Save(s interface{}) (int64, error) {
 			m.WhereStr = whereSb.ToString()
 			m.Param = values
 		}
-		return m.Update(m.FieldMap)
+		//乐观锁：附加版本号条件并自增版本号
+		var vf *fieldSchema
+		var version int64
+		if m.schema != nil {
+			vf = m.schema.versionField()
+		}
+		if vf != nil {
+			version = versionValue(s, vf)
+			m.WhereStr = Text.SpliceString("(", m.WhereStr, ") AND ", vf.Column, " = ?")
+			m.Param = append(m.Param, version)
+			m.FieldMap[vf.Column] = version + 1
+		}
+		num, err := m.Update(m.FieldMap)
+		if err == nil && vf != nil {
+			if num == 0 {
+				return num, ErrStaleObject
+			}
+			setVersionValue(s, vf, version+1)
+		}
+		if err == nil {
+			err = afterUpdate(s)
+		}
+		return num, err
 	}
 	return -1, nil
 }
 
+//根据struct的主键删除对象
+func (m *DbModel) Remove(s interface{}) (int64, error) {
+	if err := beforeDelete(s); err != nil {
+		m.ResetDbModel()
+		return -1, err
+	}
+	m.ConvertModelToMap(s)
+	if len(m.PrimaryKeys) < 1 {
+		m.ResetDbModel()
+		return -1, errors.New("未定义主键，无法删除")
+	}
+	whereSb := Text.NewString("1=1")
+	var values []interface{}
+	for k, v := range m.PrimaryKeys {
+		whereSb.Append(" AND ")
+		whereSb.Append(k)
+		whereSb.Append(" = ?")
+		values = append(values, v)
+	}
+	m.WhereStr = whereSb.ToString()
+	m.Param = values
+	num, err := m.Delete()
+	if err == nil {
+		err = afterDelete(s)
+	}
+	return num, err
+}
+
 //添加数据，用于CURD操作时的添加，通过构建map[string]interface{}添加数据
 func (m *DbModel) Insert(fieldmap map[string]interface{}) (int64, error) {
 	if m.TableName == "" || len(fieldmap) < 1 {
@@ -757,9 +916,8 @@ func (m *DbModel) Delete(pkArgs ...interface{}) (int64, error) {
 		panic("主键与值不匹配")
 	}
 	sb := Text.NewString(" WHERE ")
-	if m.WhereStr != "" { //
-		sb.Append(m.WhereStr)
-
+	if m.WhereStr != "" { //条件加括号，避免OR条件与后面附加的主键及软删除条件组合错误
+		sb.Append(Text.SpliceString("(", m.WhereStr, ")"))
 	} else {
 		sb.Append("1=1")
 	}
@@ -775,6 +933,16 @@ func (m *DbModel) Delete(pkArgs ...interface{}) (int64, error) {
 		}
 	}
 
+	//模型定义了软删除字段时，删除改为更新删除标记
+	if f := m.softDeleteField(); f != nil {
+		sql := fmt.Sprintf("UPDATE %v SET %v = ? %v AND %v", m.TableName, f.Column, sb.ToString(), f.notDeletedCondition())
+		//sql调试
+		if frame.Debug {
+			Text.Log("debug").Debug(sql)
+		}
+		values := append([]interface{}{f.deletedValue()}, m.Param...)
+		return m.Execute(MethodUpdate, sql, values...)
+	}
 	sql := fmt.Sprintf("DELETE FROM %v %v", m.TableName, sb.ToString())
 	//sql调试
 	if frame.Debug {
@@ -787,11 +955,16 @@ func (m *DbModel) Delete(pkArgs ...interface{}) (int64, error) {
 }
 
 //数据库修改操作（insert/update/delete）
-func (m *DbModel) Execute(opt string, sqlstr string, args ...interface{}) (int64, error) {
+func (m *DbModel) Execute(opt string, sqlstr string, args ...interface{}) (resnum int64, err error) {
+	var affected int64
+	table := m.TableName
+	e := m.traceBefore(opt, sqlstr, args, true)
 	defer func() { //捕捉panic错误避免崩溃
 		if r := recover(); r != nil {
 			Text.Log("db_error").Error(fmt.Sprintf("execute error:%s", r))
+			err = fmt.Errorf("%v", r)
 		}
+		m.traceAfter(e, affected, err)
 	}()
 	defer m.ResetDbModel()
 	stmt, err := m.dbWriter.Prepare(sqlstr)
@@ -799,11 +972,12 @@ func (m *DbModel) Execute(opt string, sqlstr string, args ...interface{}) (int64
 	defer stmt.Close()
 	res, err := stmt.Exec(args...)
 	checkErr(err)
-	var resnum int64
+	affected, _ = res.RowsAffected()
+	m.invalidateCache(table) //表数据已修改，原有的查询缓存失效
 	if opt == MethodInsert {
 		resnum, err = res.LastInsertId()
 	} else {
-		resnum, err = res.RowsAffected()
+		resnum = affected
 	}
 	return resnum, err
 }
@@ -821,18 +995,29 @@ func (m *DbModel) GetRow(sqlstr string, args ...interface{}) (map[string]string,
 }
 
 //数据库查询操作（select）
-func (m *DbModel) Query(sqlstr string, args ...interface{}) (*[]map[string]string, error) {
+func (m *DbModel) Query(sqlstr string, args ...interface{}) (res *[]map[string]string, err error) {
+	ret := make([]map[string]string, 0) //返回的结果集
+	var cacheKey string
+	if m.useCache {
+		cacheKey = m.queryCacheKey(sqlstr, args, "")
+		if cacheKey != "" && m.getCache(cacheKey, &ret) {
+			m.ResetDbModel()
+			return &ret, nil
+		}
+	}
+	e := m.traceBefore(MethodSelect, sqlstr, args, false)
 	defer func() { //捕捉panic错误避免崩溃
 		if r := recover(); r != nil {
 			Text.Log("sql_error").Error(fmt.Sprintf("sql error:%s", sqlstr))
 			Text.Log("sql_error").Error(fmt.Sprintf("sql error:%v", args))
 			Text.Log("db_error").Error(fmt.Sprintf("query error:%s", r))
+			res, err = &ret, fmt.Errorf("%v", r)
 		}
+		m.traceAfter(e, int64(len(ret)), err)
 	}()
 	defer m.ResetDbModel()
 	sqlstr = checkSql(sqlstr)
-	ret := make([]map[string]string, 0) //返回的结果集
-	if m.dbReader == nil {              //如果数据库实例未能获取到，返回空列表
+	if m.dbReader == nil { //如果数据库实例未能获取到，返回空列表
 		return &ret, nil
 	}
 	stmp, err := m.dbReader.Prepare(sqlstr)
@@ -863,6 +1048,9 @@ func (m *DbModel) Query(sqlstr string, args ...interface{}) (*[]map[string]strin
 		}
 		ret = append(ret, vmap)
 	}
+	if cacheKey != "" {
+		m.setCache(cacheKey, ret, m.cacheTTL)
+	}
 	return &ret, nil
 
 }
@@ -881,6 +1069,12 @@ func (m *DbModel) ResetDbModel() {
 	m.PrimaryKeys = make(map[string]interface{})
 	m.FieldMap = make(map[string]interface{})
 	m.fieldStructMap = make(map[string]string)
+	m.schema = nil
+	m.unscoped = false
+	m.counter = nil
+	m.useCache = false
+	m.cacheTTL = 0
+	m.cacheKey = ""
 }
 
 //sql语句检查
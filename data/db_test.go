@@ -0,0 +1,30 @@
+package Db
+
+import "testing"
+
+type countSoftModel struct {
+	Id        int  `field:"id" key:"pk" auto:"1" table:"count_soft"`
+	IsDeleted bool `field:"is_deleted" softdelete:"1"`
+}
+
+//统计语句包含查询条件及软删除条件，有分组时通过子查询统计分组数
+func TestBuildCountSql(t *testing.T) {
+	cases := []struct {
+		name string
+		m    *DbModel
+		want string
+	}{
+		{"plain", &DbModel{TableName: "user"}, "SELECT COUNT(1) AS total FROM user"},
+		{"where", &DbModel{TableName: "user", WhereStr: "status = ?"}, "SELECT COUNT(1) AS total FROM user WHERE status = ?"},
+		{"group", &DbModel{TableName: "user", WhereStr: "status = ?", GroupByStr: "city"},
+			"SELECT COUNT(1) AS total FROM (SELECT 1 FROM user WHERE status = ? GROUP BY city) AS t_count"},
+		{"having", &DbModel{TableName: "user", GroupByStr: "city", HavingStr: "COUNT(1) > ?"},
+			"SELECT COUNT(1) AS total FROM (SELECT 1 FROM user GROUP BY city HAVING COUNT(1) > ?) AS t_count"},
+		{"soft delete", &DbModel{TableName: "count_soft", schema: getModelSchema(&countSoftModel{})}, "SELECT COUNT(1) AS total FROM count_soft WHERE is_deleted = 0"},
+	}
+	for _, c := range cases {
+		if got := c.m.buildCountSql(); got != c.want {
+			t.Errorf("%s: SQL错误：\n%s\n应为：\n%s", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+/*
+	数据库单元测试工具，提供记录/模拟执行结果的database/sql驱动，无需真实的MySQL即可测试DbModel相关代码
+	使用方法：
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`SELECT \* FROM user WHERE id = \?`).WithArgs(1).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "hyperion"})
+	mock.ExpectExec(`UPDATE user SET`).WillReturnResult(0, 1)
+	var u UserInfo
+	err := db.Where("id = ?", 1).Find(&u)
+	//断言执行的SQL及参数
+	records := mock.Records()
+	err = mock.ExpectationsWereMet()
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sync"
+
+	"github.com/misgo/aresgo/data"
+)
+
+var (
+	AnyArg interface{} = anyArg{} //匹配任意参数值
+)
+
+type (
+	//模拟数据库，记录执行的SQL并按预设返回结果
+	Mock struct {
+		Strict bool //严格模式：未匹配到预设的SQL时返回错误，否则查询返回空结果，修改返回0行
+
+		mu           sync.Mutex
+		expectations []*Expectation
+		records      []*Record
+	}
+
+	//执行记录
+	Record struct {
+		SQL     string
+		Args    []interface{}
+		IsQuery bool //是否为查询（Query），否则为修改（Exec）
+	}
+
+	//预设的SQL及执行结果
+	Expectation struct {
+		pattern      *regexp.Regexp
+		isQuery      bool
+		args         []interface{}
+		hasArgs      bool
+		columns      []string
+		rows         [][]interface{}
+		lastInsertId int64
+		rowsAffected int64
+		err          error
+		times        int //可匹配次数，0为不限
+		used         int //已匹配次数
+	}
+
+	anyArg struct{}
+
+	//每个Mock对应一个连接器，*sql.DB通过连接器直接获取Mock，不需要按dsn登记
+	fakeConnector struct {
+		mock *Mock
+	}
+	fakeDriver struct{}
+	fakeConn   struct {
+		mock *Mock
+	}
+	fakeStmt struct {
+		conn  *fakeConn
+		query string
+	}
+	fakeTx   struct{}
+	fakeRows struct {
+		columns []string
+		rows    [][]interface{}
+		pos     int
+	}
+	fakeResult struct {
+		lastInsertId int64
+		rowsAffected int64
+	}
+)
+
+//创建模拟数据库及对应的*sql.DB，*sql.DB关闭或不再使用后Mock随之释放
+func New() (*Mock, *sql.DB, error) {
+	m := &Mock{}
+	return m, sql.OpenDB(&fakeConnector{mock: m}), nil
+}
+
+//创建使用模拟数据库的DbModel，主从库使用同一个模拟数据库
+//@param config 表前缀及字符集配置（可选）
+func NewDb(config ...*Db.DbSettings) (*Db.DbModel, *Mock) {
+	m, db, err := New()
+	if err != nil {
+		panic(err)
+	}
+	return Db.NewDbFromConn(db, db, config...), m
+}
+
+//预设查询语句
+//@param pattern 匹配SQL的正则表达式
+func (m *Mock) ExpectQuery(pattern string) *Expectation {
+	return m.expect(pattern, true)
+}
+
+//预设修改语句（insert/update/delete）
+//@param pattern 匹配SQL的正则表达式
+func (m *Mock) ExpectExec(pattern string) *Expectation {
+	return m.expect(pattern, false)
+}
+
+//添加预设
+func (m *Mock) expect(pattern string, isQuery bool) *Expectation {
+	e := &Expectation{
+		pattern: regexp.MustCompile(pattern),
+		isQuery: isQuery,
+		times:   1,
+	}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+//获取所有执行记录
+func (m *Mock) Records() []*Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Record{}, m.records...)
+}
+
+//获取最后一条执行记录，没有记录时返回nil
+func (m *Mock) LastRecord() *Record {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.records) < 1 {
+		return nil
+	}
+	return m.records[len(m.records)-1]
+}
+
+//清空预设及执行记录
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	m.expectations = nil
+	m.records = nil
+	m.mu.Unlock()
+}
+
+//检查所有预设是否都已执行
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if e.times > 0 && e.used < e.times || e.times == 0 && e.used == 0 {
+			return fmt.Errorf("预设的SQL[%s]未执行", e.pattern.String())
+		}
+	}
+	return nil
+}
+
+//记录执行的SQL并查找匹配的预设
+func (m *Mock) match(query string, args []driver.Value, isQuery bool) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	record := &Record{SQL: query, IsQuery: isQuery}
+	for _, v := range args {
+		record.Args = append(record.Args, v)
+	}
+	m.records = append(m.records, record)
+	for _, e := range m.expectations {
+		if e.isQuery != isQuery || e.times > 0 && e.used >= e.times {
+			continue
+		}
+		if !e.pattern.MatchString(query) || !e.matchArgs(args) {
+			continue
+		}
+		e.used++
+		return e, nil
+	}
+	if m.Strict {
+		return nil, fmt.Errorf("dbtest: 未找到匹配的预设SQL[%s]%v", query, record.Args)
+	}
+	return nil, nil
+}
+
+//设置匹配的参数，可使用AnyArg匹配任意值
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+//设置查询返回的数据
+//@param columns 字段名列表
+//@param rows 每行的值，与字段名一一对应
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+//设置修改返回的结果
+func (e *Expectation) WillReturnResult(lastInsertId int64, rowsAffected int64) *Expectation {
+	e.lastInsertId = lastInsertId
+	e.rowsAffected = rowsAffected
+	return e
+}
+
+//设置返回的错误
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+//设置可匹配的次数，0为不限次数
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+//判断参数是否匹配
+func (e *Expectation) matchArgs(args []driver.Value) bool {
+	if !e.hasArgs {
+		return true
+	}
+	if len(args) != len(e.args) {
+		return false
+	}
+	for i, v := range e.args {
+		if _, ok := v.(anyArg); ok {
+			continue
+		}
+		dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return false
+		}
+		av := args[i]
+		if b, ok := av.([]byte); ok { //[]byte与string按内容比较
+			av = string(b)
+		}
+		if b, ok := dv.([]byte); ok {
+			dv = string(b)
+		}
+		if !reflect.DeepEqual(dv, av) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{mock: c.mock}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return &fakeDriver{}
+}
+
+//只能通过New创建的连接器打开
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return nil, errors.New("dbtest: 请使用dbtest.New创建模拟数据库")
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+func (t *fakeTx) Commit() error {
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	return nil
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+//参数个数不做检查
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	e, err := s.conn.mock.match(s.query, args, false)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return &fakeResult{}, nil
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &fakeResult{lastInsertId: e.lastInsertId, rowsAffected: e.rowsAffected}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	e, err := s.conn.mock.match(s.query, args, true)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return &fakeRows{}, nil
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &fakeRows{columns: e.columns, rows: e.rows}, nil
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	row := r.rows[r.pos]
+	r.pos++
+	if len(row) != len(r.columns) {
+		return errors.New("dbtest: 返回数据与字段个数不一致")
+	}
+	for i, v := range row {
+		dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return err
+		}
+		dest[i] = dv
+	}
+	return nil
+}
+
+func (r *fakeResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r *fakeResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
@@ -0,0 +1,204 @@
+package dbtest_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/misgo/aresgo/data"
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+type user struct {
+	Id      int    `field:"id" key:"pk" auto:"1" table:"user"`
+	Name    string `field:"name"`
+	Version int    `field:"version" version:"1"`
+
+	calls     []string //已调用的钩子
+	deleteErr error    //BeforeDelete返回的错误
+}
+
+func (u *user) BeforeInsert() error {
+	u.calls = append(u.calls, "BeforeInsert")
+	return nil
+}
+
+func (u *user) AfterInsert() error {
+	u.calls = append(u.calls, "AfterInsert")
+	return nil
+}
+
+func (u *user) AfterUpdate() error {
+	u.calls = append(u.calls, "AfterUpdate")
+	return nil
+}
+
+func (u *user) BeforeDelete() error {
+	u.calls = append(u.calls, "BeforeDelete")
+	return u.deleteErr
+}
+
+func (u *user) AfterFind() error {
+	u.calls = append(u.calls, "AfterFind")
+	return nil
+}
+
+var userColumns = []string{"id", "name", "version"}
+
+func TestDbModelThroughMock(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock)
+	}{
+		{"Find", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectQuery(`^SELECT \* FROM user WHERE id = \?$`).WithArgs(1).
+				WillReturnRows(userColumns, []interface{}{1, "hyperion", 3})
+			var u user
+			if err := db.Where("id = ?", 1).Find(&u); err != nil {
+				t.Fatal(err)
+			}
+			if u.Id != 1 || u.Name != "hyperion" || u.Version != 3 || !called(&u, "AfterFind") {
+				t.Fatalf("查询结果错误：%+v", u)
+			}
+		}},
+		{"FindNoRows", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectQuery(`^SELECT \* FROM user WHERE id = \?$`).WillReturnRows(userColumns)
+			var u user
+			if err := db.Where("id = ?", 2).Find(&u); err == nil {
+				t.Fatal("没有数据时应返回错误")
+			}
+		}},
+		{"FindList", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectQuery(`^SELECT \* FROM user ORDER BY id$`).
+				WillReturnRows(userColumns, []interface{}{1, "a", 0}, []interface{}{2, "b", 0})
+			var list []user
+			if err := db.OrderBy("id").FindList(&list); err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 || list[1].Name != "b" {
+				t.Fatalf("查询结果错误：%+v", list)
+			}
+		}},
+		{"AddHooksAndAutoIncrement", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectExec(`^INSERT INTO user \(`).WillReturnResult(7, 1)
+			u := &user{Name: "new"}
+			if _, err := db.Add(u); err != nil {
+				t.Fatal(err)
+			}
+			if u.Id != 7 || !called(u, "BeforeInsert") || !called(u, "AfterInsert") {
+				t.Fatalf("添加后状态错误：%+v", u)
+			}
+		}},
+		{"SaveVersion", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectExec(`^UPDATE user SET .* WHERE \(1=1 AND id = \?\) AND version = \?$`).
+				WithArgs(dbtest.AnyArg, dbtest.AnyArg, 5, 2).WillReturnResult(0, 1)
+			u := &user{Id: 5, Name: "n", Version: 2}
+			if _, err := db.Save(u); err != nil {
+				t.Fatal(err)
+			}
+			if u.Version != 3 || !called(u, "AfterUpdate") {
+				t.Fatalf("保存后状态错误：%+v", u)
+			}
+		}},
+		{"SaveStale", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectExec(`^UPDATE user SET`).WillReturnResult(0, 0)
+			u := &user{Id: 5, Version: 2}
+			if _, err := db.Save(u); err != Db.ErrStaleObject {
+				t.Fatalf("版本号不一致时应返回ErrStaleObject，实际为%v", err)
+			}
+			if u.Version != 2 || called(u, "AfterUpdate") {
+				t.Fatalf("保存失败不应修改版本号及调用AfterUpdate：%+v", u)
+			}
+		}},
+		{"Remove", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectExec(`^DELETE FROM user  WHERE \(1=1 AND id = \?\)$`).WithArgs(9).WillReturnResult(0, 1)
+			if n, err := db.Remove(&user{Id: 9}); err != nil || n != 1 {
+				t.Fatalf("删除结果错误：%d %v", n, err)
+			}
+		}},
+		{"RemoveAbortedByHook", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			u := &user{Id: 9, deleteErr: errors.New("禁止删除")}
+			if _, err := db.Remove(u); err != u.deleteErr {
+				t.Fatalf("BeforeDelete的错误应返回，实际为%v", err)
+			}
+			if len(mock.Records()) > 0 {
+				t.Fatal("BeforeDelete返回错误时不应执行SQL")
+			}
+		}},
+		{"Paginate", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectQuery(`^SELECT COUNT\(1\) AS total FROM user WHERE status = \?$`).WithArgs(1).
+				WillReturnRows([]string{"total"}, []interface{}{3})
+			mock.ExpectQuery(`^SELECT \* FROM user WHERE status = \? ORDER BY id LIMIT 2,2$`).WithArgs(1).
+				WillReturnRows(userColumns, []interface{}{3, "c", 0})
+			var list []user
+			page, err := db.Where("status = ?", 1).OrderBy("id").Paginate(2, 2, &list)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 3 || page.PageCount != 2 || page.HasNext || !page.HasPrev || len(list) != 1 {
+				t.Fatalf("分页结果错误：%+v %+v", page, list)
+			}
+		}},
+		{"PaginateOutOfRange", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			mock.ExpectQuery(`^SELECT COUNT\(1\)`).WillReturnRows([]string{"total"}, []interface{}{3})
+			var list []user
+			if _, err := db.Paginate(5, 2, &list); err != nil || len(list) != 0 {
+				t.Fatalf("超出范围应返回空列表：%v %+v", err, list)
+			}
+			if len(mock.Records()) != 1 {
+				t.Fatal("超出范围不应查询列表")
+			}
+		}},
+		{"ReturnError", func(t *testing.T, db *Db.DbModel, mock *dbtest.Mock) {
+			want := errors.New("deadlock")
+			mock.ExpectExec(`^UPDATE user`).WillReturnError(want)
+			if _, err := db.Table("user").Where("id = ?", 1).Update(map[string]interface{}{"name": "x"}); err == nil || !strings.Contains(err.Error(), want.Error()) {
+				t.Fatalf("应返回预设的错误，实际为%v", err)
+			}
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db, mock := dbtest.NewDb()
+			defer db.Close()
+			c.run(t, db, mock)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+//严格模式下未匹配的SQL返回错误，非严格模式返回空结果
+func TestMockStrict(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	defer db.Close()
+	if _, err := db.Table("user").Select(); err != nil {
+		t.Fatalf("非严格模式不应返回错误：%v", err)
+	}
+	mock.Strict = true
+	if _, err := db.Table("user").Select(); err == nil {
+		t.Fatal("严格模式未匹配的SQL应返回错误")
+	}
+	if len(mock.Records()) != 2 {
+		t.Fatalf("执行记录个数错误：%d", len(mock.Records()))
+	}
+}
+
+//预设未执行时ExpectationsWereMet返回错误
+func TestExpectationsWereMet(t *testing.T) {
+	_, mock := dbtest.NewDb()
+	mock.ExpectQuery(`SELECT`)
+	if err := mock.ExpectationsWereMet(); err == nil {
+		t.Fatal("预设未执行时应返回错误")
+	}
+}
+
+func called(u *user, hook string) bool {
+	for _, c := range u.calls {
+		if c == hook {
+			return true
+		}
+	}
+	return false
+}
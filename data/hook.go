@@ -0,0 +1,192 @@
+/*
+	模型生命周期钩子，struct实现对应的接口后，在Add/Save/Remove/Find等操作前后自动调用
+	Before系列方法返回错误时终止操作；After系列方法返回的错误会作为操作结果返回
+	使用方法：
+	func (u *UserInfo) AfterUpdate() error {
+		aresgo.R("default").Del(fmt.Sprintf("user_%d", u.Id))
+		return nil
+	}
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type (
+	//添加数据前执行
+	BeforeInsertHook interface {
+		BeforeInsert() error
+	}
+	//添加数据后执行（自增主键已回写到struct）
+	AfterInsertHook interface {
+		AfterInsert() error
+	}
+	//保存数据前执行
+	BeforeUpdateHook interface {
+		BeforeUpdate() error
+	}
+	//保存数据后执行
+	AfterUpdateHook interface {
+		AfterUpdate() error
+	}
+	//删除数据前执行
+	BeforeDeleteHook interface {
+		BeforeDelete() error
+	}
+	//删除数据后执行
+	AfterDeleteHook interface {
+		AfterDelete() error
+	}
+	//查询数据并映射到struct后执行
+	AfterFindHook interface {
+		AfterFind() error
+	}
+)
+
+//调用添加前的钩子并设置自动时间
+func beforeInsert(s interface{}) error {
+	if h, ok := s.(BeforeInsertHook); ok {
+		if err := h.BeforeInsert(); err != nil {
+			return err
+		}
+	}
+	setAutoTime(s, true)
+	return nil
+}
+
+//调用添加后的钩子
+func afterInsert(s interface{}) error {
+	if h, ok := s.(AfterInsertHook); ok {
+		return h.AfterInsert()
+	}
+	return nil
+}
+
+//调用保存前的钩子并设置自动时间
+func beforeUpdate(s interface{}) error {
+	if h, ok := s.(BeforeUpdateHook); ok {
+		if err := h.BeforeUpdate(); err != nil {
+			return err
+		}
+	}
+	setAutoTime(s, false)
+	return nil
+}
+
+//调用保存后的钩子
+func afterUpdate(s interface{}) error {
+	if h, ok := s.(AfterUpdateHook); ok {
+		return h.AfterUpdate()
+	}
+	return nil
+}
+
+//调用删除前的钩子
+func beforeDelete(s interface{}) error {
+	if h, ok := s.(BeforeDeleteHook); ok {
+		return h.BeforeDelete()
+	}
+	return nil
+}
+
+//调用删除后的钩子
+func afterDelete(s interface{}) error {
+	if h, ok := s.(AfterDeleteHook); ok {
+		return h.AfterDelete()
+	}
+	return nil
+}
+
+//调用查询后的钩子
+func afterFind(s interface{}) error {
+	if h, ok := s.(AfterFindHook); ok {
+		return h.AfterFind()
+	}
+	return nil
+}
+
+//设置autoCreateTime/autoUpdateTime标签字段的值，struct必须为指针
+//time.Time字段写入当前时间（按type标签方式存储），整型字段写入unix时间戳
+//字符串字段按type标签格式化：date为“2006-01-02”，int为unix时间戳，其他为“2006-01-02 15:04:05”
+//@param isCreate 是否为添加操作，添加时autoCreateTime字段仅在未赋值时写入
+func setAutoTime(s interface{}, isCreate bool) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	rv = rv.Elem()
+	ms := getModelSchema(s)
+	now := time.Now()
+	for _, f := range ms.Fields {
+		if !(f.AutoUpdateTime || f.AutoCreateTime && isCreate) {
+			continue
+		}
+		fv := rv.FieldByName(f.Name)
+		if !fv.CanSet() {
+			continue
+		}
+		if f.AutoCreateTime && !f.AutoUpdateTime && !isZeroValue(fv) {
+			continue
+		}
+		if f.IsTime {
+			fv.Set(reflect.ValueOf(now))
+		} else if f.isInteger() {
+			setIntValue(fv, now.Unix())
+		} else if f.Kind == reflect.String {
+			fv.SetString(formatAutoTime(now, f.TimeType))
+		}
+	}
+}
+
+//按type标签将时间格式化为字符串
+func formatAutoTime(t time.Time, timeType string) string {
+	switch timeType {
+	case "date":
+		return t.Format("2006-01-02")
+	case "int":
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+//添加成功后将自增主键回写到struct
+func setAutoIncrement(s interface{}, id int64) {
+	rv := reflect.ValueOf(s)
+	if id <= 0 || rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+	rv = rv.Elem()
+	for _, f := range getModelSchema(s).Fields {
+		if f.IsPK && f.IsAuto && f.isInteger() {
+			fv := rv.FieldByName(f.Name)
+			if fv.CanSet() && isZeroValue(fv) {
+				setIntValue(fv, id)
+			}
+			return
+		}
+	}
+}
+
+//给整型字段赋值（兼容有符号及无符号整型）
+func setIntValue(fv reflect.Value, val int64) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fv.SetInt(val)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fv.SetUint(uint64(val))
+	}
+}
+
+//判断字段值是否为零值
+func isZeroValue(fv reflect.Value) bool {
+	if t, ok := fv.Interface().(time.Time); ok {
+		return t.IsZero()
+	}
+	return fv.IsZero()
+}
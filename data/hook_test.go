@@ -0,0 +1,151 @@
+package Db_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+type hookUser struct {
+	Id        int64     `field:"id" key:"pk" auto:"1" table:"hook_user"`
+	Name      string    `field:"name"`
+	CreatedAt string    `field:"created_at" autoCreateTime:"1"`
+	Birthday  string    `field:"birthday" type:"date" autoCreateTime:"1"`
+	UpdatedAt int64     `field:"updated_at" autoUpdateTime:"1"`
+	SavedAt   time.Time `field:"saved_at" type:"datetime" autoUpdateTime:"1"`
+	calls     []string
+	fail      string
+}
+
+func (u *hookUser) hook(name string) error {
+	u.calls = append(u.calls, name)
+	if u.fail == name {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func (u *hookUser) BeforeInsert() error { return u.hook("BeforeInsert") }
+func (u *hookUser) AfterInsert() error {
+	return u.hook("AfterInsert:" + strings.SplitN(u.CreatedAt, "-", 2)[0])
+}
+func (u *hookUser) BeforeUpdate() error { return u.hook("BeforeUpdate") }
+func (u *hookUser) AfterUpdate() error  { return u.hook("AfterUpdate") }
+func (u *hookUser) BeforeDelete() error { return u.hook("BeforeDelete") }
+func (u *hookUser) AfterDelete() error  { return u.hook("AfterDelete") }
+func (u *hookUser) AfterFind() error    { return u.hook("AfterFind:" + u.Name) }
+
+//添加时先调用BeforeInsert并写入自动时间，成功后回写自增主键再调用AfterInsert
+func TestAddHooksAndAutoFields(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectExec(`^INSERT INTO hook_user`).WillReturnResult(42, 1)
+	u := &hookUser{Name: "a", Birthday: "2000-01-01"}
+	if _, err := db.Add(u); err != nil {
+		t.Fatal(err)
+	}
+	year := time.Now().Format("2006")
+	if strings.Join(u.calls, ",") != "BeforeInsert,AfterInsert:"+year {
+		t.Errorf("钩子调用顺序错误：%v", u.calls)
+	}
+	if u.Id != 42 {
+		t.Errorf("自增主键应回写到struct，实际为%d", u.Id)
+	}
+	if _, err := time.Parse("2006-01-02 15:04:05", u.CreatedAt); err != nil {
+		t.Errorf("字符串字段应写入datetime格式的时间：%q", u.CreatedAt)
+	}
+	if u.Birthday != "2000-01-01" {
+		t.Errorf("已赋值的autoCreateTime字段不应覆盖：%q", u.Birthday)
+	}
+	if u.UpdatedAt < time.Now().Add(-time.Minute).Unix() || u.SavedAt.IsZero() {
+		t.Errorf("autoUpdateTime字段应写入当前时间：%d %v", u.UpdatedAt, u.SavedAt)
+	}
+	args := insertArgs(t, mock.LastRecord())
+	if args["created_at"] != u.CreatedAt || args["updated_at"] != u.UpdatedAt || args["saved_at"] != u.SavedAt.Format("2006-01-02 15:04:05") {
+		t.Errorf("自动时间应写入数据库：%v", args)
+	}
+	if _, ok := args["id"]; ok {
+		t.Errorf("自增主键不应写入：%v", args)
+	}
+
+	d := &hookUser{}
+	db.Table("hook_user").Add(d)
+	if _, err := time.Parse("2006-01-02", d.Birthday); err != nil {
+		t.Errorf("type为date的字符串字段应写入日期：%q", d.Birthday)
+	}
+}
+
+//保存及删除前后调用钩子，保存时更新autoUpdateTime字段，不修改autoCreateTime字段
+func TestSaveRemoveHooks(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectExec(`^UPDATE hook_user`).WillReturnResult(0, 1)
+	mock.ExpectExec(`^DELETE FROM hook_user`).WillReturnResult(0, 1)
+	u := &hookUser{Id: 1, CreatedAt: "2000-01-01 00:00:00", UpdatedAt: 1}
+	if _, err := db.Save(u); err != nil {
+		t.Fatal(err)
+	}
+	if u.CreatedAt != "2000-01-01 00:00:00" || u.UpdatedAt <= 1 {
+		t.Errorf("保存时只更新autoUpdateTime字段：%q %d", u.CreatedAt, u.UpdatedAt)
+	}
+	if _, err := db.Remove(u); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(u.calls, ",") != "BeforeUpdate,AfterUpdate,BeforeDelete,AfterDelete" {
+		t.Errorf("钩子调用顺序错误：%v", u.calls)
+	}
+}
+
+//查询映射到struct后调用AfterFind
+func TestFindHook(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`FROM hook_user`).WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"})
+	u := &hookUser{}
+	if err := db.Where("id = ?", 1).Find(u); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(u.calls, ",") != "AfterFind:a" {
+		t.Errorf("钩子调用错误：%v", u.calls)
+	}
+}
+
+//Before钩子返回错误时终止操作，不执行SQL
+func TestBeforeHookAborts(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	for _, name := range []string{"BeforeInsert", "BeforeUpdate", "BeforeDelete"} {
+		u := &hookUser{Id: 1, fail: name}
+		var err error
+		switch name {
+		case "BeforeInsert":
+			_, err = db.Add(u)
+		case "BeforeUpdate":
+			_, err = db.Save(u)
+		default:
+			_, err = db.Remove(u)
+		}
+		if err == nil || err.Error() != name+" failed" {
+			t.Errorf("%s: 应返回钩子的错误，实际为%v", name, err)
+		}
+		if u.CreatedAt != "" || u.UpdatedAt != 0 {
+			t.Errorf("%s: 终止时不应写入自动时间", name)
+		}
+	}
+	if n := len(mock.Records()); n != 0 {
+		t.Errorf("Before钩子返回错误时不应执行SQL，实际执行%d次", n)
+	}
+}
+
+//按INSERT语句的字段顺序获取参数
+func insertArgs(t *testing.T, r *dbtest.Record) map[string]interface{} {
+	m := regexp.MustCompile(`\(([^)]*)\) VALUES`).FindStringSubmatch(r.SQL)
+	if m == nil {
+		t.Fatalf("INSERT语句格式错误：%s", r.SQL)
+	}
+	args := make(map[string]interface{})
+	for i, col := range strings.Split(m[1], ",") {
+		args[strings.Trim(strings.TrimSpace(col), "`")] = r.Args[i]
+	}
+	return args
+}
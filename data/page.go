@@ -0,0 +1,283 @@
+/*
+	分页查询，返回数据列表及分页信息（总数、总页数、是否有下一页）
+	使用方法：
+	var list []UserInfo
+	page, err := D("dev").Where("status = ?", 1).OrderBy("id DESC").Paginate(1, 20, &list)
+	//游标分页（按排序字段及主键定位，不统计总数），下一页传入上一页返回的NextCursor
+	page, err := D("dev").Where("status = ?", 1).OrderBy("created_at DESC").PaginateCursor("", 20, &list)
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
+)
+
+const (
+	DefaultPageSize = 20 //默认每页数量
+)
+
+var (
+	ErrInvalidCursor = errors.New("游标无效") //游标无法解析或与排序字段不匹配
+)
+
+type (
+	//分页信息
+	Page struct {
+		Page       int    `json:"page"`                  //当前页码，从1开始
+		PageSize   int    `json:"page_size"`             //每页数量
+		Total      int    `json:"total"`                 //记录总数（游标分页不统计）
+		PageCount  int    `json:"page_count"`            //总页数（游标分页不统计）
+		HasPrev    bool   `json:"has_prev"`              //是否有上一页
+		HasNext    bool   `json:"has_next"`              //是否有下一页
+		NextCursor string `json:"next_cursor,omitempty"` //下一页的游标（游标分页使用）
+	}
+)
+
+//分页查询，统计总数并将当前页的数据映射到struct列表
+//@param page 页码，从1开始
+//@param pageSize 每页数量，小于1时使用DefaultPageSize
+//@param structList struct切片的指针
+func (m *DbModel) Paginate(page int, pageSize int, structList interface{}) (*Page, error) {
+	rv := reflect.ValueOf(structList)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		m.ResetDbModel()
+		return nil, errors.New("获取的数据类型必须为切片指针")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	m.ConvertModelToMap(reflect.New(rv.Elem().Type().Elem()).Interface()) //将字段结构转换map
+	st := m.saveState()
+	total, err := m.count()
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{
+		Page:      page,
+		PageSize:  pageSize,
+		Total:     total,
+		PageCount: (total + pageSize - 1) / pageSize,
+		HasPrev:   page > 1,
+	}
+	p.HasNext = page < p.PageCount
+	rv.Elem().Set(reflect.MakeSlice(rv.Elem().Type(), 0, 0))
+	if (page-1)*pageSize >= total { //超出范围，返回空列表
+		return p, nil
+	}
+	m.restoreState(st)
+	m.Offset = (page - 1) * pageSize
+	m.RowsNum = pageSize
+	rows, err := m.selectRows()
+	if err != nil {
+		return p, err
+	}
+	list, err := m.mapsToList(rv.Elem().Type(), rows)
+	if err != nil {
+		return p, err
+	}
+	rv.Elem().Set(list)
+	return p, nil
+}
+
+//游标分页，按排序字段定位下一页，不统计总数，适用于数据量大或实时变化的列表
+//需要struct模型定义唯一主键（key:"pk"）；OrderBy只能设置一个字段（可再附加同方向的主键），未设置时按主键升序
+//排序字段不是主键时使用主键区分相同的值，排序字段的值不能为NULL
+//@param cursor 游标，第一页传空字符串，后续页传入上一页返回的NextCursor
+//@param pageSize 每页数量，小于1时使用DefaultPageSize
+//@param structList struct切片的指针
+func (m *DbModel) PaginateCursor(cursor string, pageSize int, structList interface{}) (*Page, error) {
+	rv := reflect.ValueOf(structList)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		m.ResetDbModel()
+		return nil, errors.New("获取的数据类型必须为切片指针")
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	m.ConvertModelToMap(reflect.New(rv.Elem().Type().Elem()).Interface()) //将字段结构转换map
+	if len(m.PrimaryKeys) != 1 {
+		m.ResetDbModel()
+		return nil, errors.New("游标分页必须设置唯一的主键")
+	}
+	var pk string
+	for k := range m.PrimaryKeys {
+		pk = k
+	}
+	col, desc, err := parseCursorOrder(m.Order, pk)
+	if err != nil {
+		m.ResetDbModel()
+		return nil, err
+	}
+	//根据排序方向确定游标条件
+	op, dir := " > ?", " ASC"
+	if desc {
+		op, dir = " < ?", " DESC"
+	}
+	if cursor != "" {
+		var cond string
+		var args []interface{}
+		if col == pk {
+			cond, args = Text.SpliceString(pk, op), []interface{}{cursor}
+		} else {
+			values, err := decodeCursor(cursor)
+			if err != nil {
+				m.ResetDbModel()
+				return nil, err
+			}
+			cond = Text.SpliceString("(", col, op, " OR (", col, " = ? AND ", pk, op, "))")
+			args = []interface{}{values[0], values[0], values[1]}
+		}
+		if m.WhereStr == "" {
+			m.WhereStr = cond
+		} else {
+			m.WhereStr = Text.SpliceString("(", m.WhereStr, ") AND ", cond)
+		}
+		m.Param = append(m.Param, args...)
+	}
+	if col == pk {
+		m.Order = Text.SpliceString(pk, dir)
+	} else {
+		m.Order = Text.SpliceString(col, dir, ", ", pk, dir)
+	}
+	m.Offset = 0
+	m.RowsNum = pageSize + 1 //多查询一条用来判断是否有下一页
+	rows, err := m.selectRows()
+	if err != nil {
+		return nil, err
+	}
+	p := &Page{
+		Page:     1,
+		PageSize: pageSize,
+		HasPrev:  cursor != "",
+	}
+	if len(rows) > pageSize {
+		rows = rows[:pageSize]
+		p.HasNext = true
+	}
+	if p.HasNext {
+		last := rows[len(rows)-1]
+		if col == pk {
+			p.NextCursor = last[pk]
+		} else {
+			p.NextCursor = encodeCursor(last[columnKey(col)], last[pk])
+		}
+	}
+	list, err := m.mapsToList(rv.Elem().Type(), rows)
+	if err != nil {
+		return p, err
+	}
+	rv.Elem().Set(list)
+	return p, nil
+}
+
+//解析游标分页的排序，返回排序字段及是否倒序
+//只支持一个排序字段，第二个字段只能是同方向的主键
+func parseCursorOrder(order string, pk string) (string, bool, error) {
+	if strings.TrimSpace(order) == "" {
+		return pk, false, nil
+	}
+	var cols []string
+	var dirs []bool
+	for _, item := range strings.Split(order, ",") {
+		fields := strings.Fields(item)
+		if len(fields) < 1 || len(fields) > 2 {
+			return "", false, errors.New("游标分页的排序格式错误")
+		}
+		desc := false
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "DESC":
+				desc = true
+			case "ASC":
+			default:
+				return "", false, errors.New("游标分页的排序格式错误")
+			}
+		}
+		cols = append(cols, fields[0])
+		dirs = append(dirs, desc)
+	}
+	if len(cols) == 2 && columnKey(cols[1]) == pk && dirs[0] == dirs[1] {
+		cols, dirs = cols[:1], dirs[:1]
+	}
+	if len(cols) != 1 {
+		return "", false, errors.New("游标分页只支持按一个字段排序（可附加同方向的主键）")
+	}
+	if columnKey(cols[0]) == pk {
+		return pk, dirs[0], nil
+	}
+	return cols[0], dirs[0], nil
+}
+
+//查询结果中字段对应的Key（去掉表名或别名）
+func columnKey(col string) string {
+	if pos := strings.LastIndex(col, "."); pos >= 0 {
+		col = col[pos+1:]
+	}
+	return strings.Trim(col, "`")
+}
+
+//生成游标：排序字段的值及主键值
+func encodeCursor(value string, pk string) string {
+	data, _ := json.Marshal([]string{value, pk})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+//解析游标，返回排序字段的值及主键值
+func decodeCursor(cursor string) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var values []string
+	if err = json.Unmarshal(data, &values); err != nil || len(values) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	return values, nil
+}
+
+//按当前的查询条件查询出数据列表并返回错误信息
+func (m *DbModel) selectRows() ([]map[string]string, error) {
+	defer m.ResetDbModel()
+	if m.dbReader == nil {
+		return nil, errors.New("数据库实例未初始化")
+	}
+	sqlstr := m.buildSelectSql()
+	//SQL调试
+	if frame.Debug {
+		Text.Log("debug").Debug(sqlstr)
+	}
+	list := make([]map[string]string, 0)
+	var cacheKey string
+	if m.useCache {
+		cacheKey = m.queryCacheKey(sqlstr, m.Param, "")
+		if cacheKey != "" && m.getCache(cacheKey, &list) {
+			return list, nil
+		}
+	}
+	e := m.traceBefore(MethodSelect, sqlstr, m.Param, false)
+	rows, err := m.dbReader.Query(sqlstr, m.Param...)
+	if err == nil {
+		err = eachRow(rows, func(row map[string]string) error {
+			list = append(list, row)
+			return nil
+		})
+	}
+	m.traceAfter(e, int64(len(list)), err)
+	if err == nil && cacheKey != "" {
+		m.setCache(cacheKey, list, m.cacheTTL)
+	}
+	return list, err
+}
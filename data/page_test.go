@@ -0,0 +1,91 @@
+package Db_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+type pageUser struct {
+	Id        int    `field:"id" key:"pk" auto:"1" table:"user"`
+	Name      string `field:"name"`
+	CreatedAt string `field:"created_at"`
+}
+
+//游标按实际的排序字段生成，下一页使用排序字段及主键定位
+func TestPaginateCursorOrderColumn(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`ORDER BY created_at DESC, id DESC LIMIT 0,3$`).
+		WillReturnRows([]string{"id", "name", "created_at"},
+			[]interface{}{9, "a", "2026-10-03"},
+			[]interface{}{4, "b", "2026-10-02"},
+			[]interface{}{7, "c", "2026-10-01"})
+	var list []pageUser
+	page, err := db.Where("status = ?", 1).OrderBy("created_at DESC").PaginateCursor("", 2, &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[1].Id != 4 || !page.HasNext || page.NextCursor == "" {
+		t.Fatalf("第一页结果错误：%+v %+v", list, page)
+	}
+
+	mock.ExpectQuery(`WHERE \(status = \?\) AND \(created_at < \? OR \(created_at = \? AND id < \?\)\) ORDER BY created_at DESC, id DESC`).
+		WithArgs(1, "2026-10-02", "2026-10-02", "4").
+		WillReturnRows([]string{"id", "name", "created_at"}, []interface{}{7, "c", "2026-10-01"})
+	page, err = db.Where("status = ?", 1).OrderBy("created_at DESC").PaginateCursor(page.NextCursor, 2, &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Id != 7 || page.HasNext || !page.HasPrev {
+		t.Fatalf("第二页结果错误：%+v %+v", list, page)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//按主键排序时游标为主键值
+func TestPaginateCursorPrimaryKey(t *testing.T) {
+	cases := []struct {
+		order string
+		sql   string
+	}{
+		{"", "WHERE id > ? ORDER BY id ASC LIMIT 0,11"},
+		{"id DESC", "WHERE id < ? ORDER BY id DESC LIMIT 0,11"},
+		{"id asc", "WHERE id > ? ORDER BY id ASC LIMIT 0,11"},
+	}
+	for _, c := range cases {
+		db, mock := dbtest.NewDb()
+		var list []pageUser
+		if _, err := db.OrderBy(c.order).PaginateCursor("10", 10, &list); err != nil {
+			t.Fatalf("%q: %v", c.order, err)
+		}
+		if r := mock.LastRecord(); !strings.HasSuffix(r.SQL, c.sql) || len(r.Args) != 1 || r.Args[0] != "10" {
+			t.Errorf("%q: SQL错误：%s %v", c.order, r.SQL, r.Args)
+		}
+	}
+}
+
+//不支持的排序及无效游标返回错误，不执行查询
+func TestPaginateCursorErrors(t *testing.T) {
+	cases := []struct {
+		order  string
+		cursor string
+	}{
+		{"created_at DESC, name ASC", ""},
+		{"created_at DESC, id ASC", ""},
+		{"created_at sideways", ""},
+		{"created_at DESC", "not-a-cursor"},
+	}
+	for _, c := range cases {
+		db, mock := dbtest.NewDb()
+		var list []pageUser
+		if _, err := db.OrderBy(c.order).PaginateCursor(c.cursor, 10, &list); err == nil {
+			t.Errorf("%q %q: 应返回错误", c.order, c.cursor)
+		}
+		if len(mock.Records()) > 0 {
+			t.Errorf("%q %q: 不应执行查询", c.order, c.cursor)
+		}
+	}
+}
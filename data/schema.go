@@ -0,0 +1,159 @@
+/*
+	struct模型结构解析，根据struct tag生成字段映射信息并缓存
+	支持的标签：
+	field:数据库字段名；key:主键为pk；table:表名；type:时间存储方式（date/datetime/int）；auto:数据库自动生成字段
+	size:字段长度；default:字段默认值；index:普通索引名；unique:唯一索引名（值为1时自动生成索引名，多个索引用逗号分隔）
+	autoCreateTime:添加时自动写入当前时间；autoUpdateTime:添加及保存时自动写入当前时间（时间按type标签的方式存储）
+	softdelete:软删除标记字段；version:乐观锁版本号字段
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	//struct字段与数据库字段的映射信息
+	fieldSchema struct {
+		Name       string //struct字段名
+		Column     string //数据库字段名
+		Kind       reflect.Kind
+		IsTime     bool   //是否为time.Time类型
+		TimeType   string //时间的存储方式：date/datetime/int
+		IsPK       bool   //是否为主键
+		IsAuto     bool   //是否为数据库自动生成字段（如自增主键）
+		Size       int    //字段长度
+		Default    string //默认值
+		HasDefault bool   //是否设置了默认值
+		Indexes    []string
+		Uniques    []string
+
+		AutoCreateTime bool //添加时自动设置为当前时间
+		AutoUpdateTime bool //添加及保存时自动设置为当前时间
+		SoftDelete     bool //软删除标记字段
+		IsVersion      bool //乐观锁版本号字段
+	}
+
+	//struct模型结构信息
+	modelSchema struct {
+		Table  string //table标签定义的表名（不含表前缀）
+		Fields []*fieldSchema
+	}
+)
+
+var (
+	schemaCache   map[reflect.Type]*modelSchema = make(map[reflect.Type]*modelSchema) //模型结构缓存
+	schemaCacheMu sync.RWMutex
+)
+
+//获取struct模型结构信息，解析结果按类型缓存
+//@param s struct对象、struct指针或struct切片
+func getModelSchema(s interface{}) *modelSchema {
+	rt := reflect.TypeOf(s)
+	for rt != nil && (rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice) {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil
+	}
+	schemaCacheMu.RLock()
+	ms, ok := schemaCache[rt]
+	schemaCacheMu.RUnlock()
+	if ok {
+		return ms
+	}
+	ms = parseModelSchema(rt)
+	schemaCacheMu.Lock()
+	schemaCache[rt] = ms
+	schemaCacheMu.Unlock()
+	return ms
+}
+
+//解析struct的标签
+func parseModelSchema(rt reflect.Type) *modelSchema {
+	ms := &modelSchema{}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if tagTable := field.Tag.Get("table"); tagTable != "" && ms.Table == "" { //取第一个定义的table
+			ms.Table = tagTable
+		}
+		tagField := field.Tag.Get("field")
+		if tagField == "" { //不属于数据库字段
+			continue
+		}
+		fs := &fieldSchema{
+			Name:     field.Name,
+			Column:   tagField,
+			Kind:     field.Type.Kind(),
+			IsTime:   field.Type.Kind() == reflect.Struct && field.Type.String() == "time.Time",
+			TimeType: field.Tag.Get("type"),
+			IsPK:     strings.ToLower(field.Tag.Get("key")) == "pk",
+			IsAuto:   strings.Trim(field.Tag.Get("auto"), " ") == "1",
+			Indexes:  splitIndexTag(field.Tag.Get("index"), "idx_", tagField),
+			Uniques:  splitIndexTag(field.Tag.Get("unique"), "uk_", tagField),
+		}
+		if size, err := strconv.Atoi(field.Tag.Get("size")); err == nil {
+			fs.Size = size
+		}
+		fs.Default, fs.HasDefault = field.Tag.Lookup("default")
+		fs.AutoCreateTime = field.Tag.Get("autoCreateTime") == "1"
+		fs.AutoUpdateTime = field.Tag.Get("autoUpdateTime") == "1"
+		fs.SoftDelete = field.Tag.Get("softdelete") == "1"
+		fs.IsVersion = field.Tag.Get("version") == "1"
+		ms.Fields = append(ms.Fields, fs)
+	}
+	return ms
+}
+
+//解析索引标签，值为1时以“前缀+字段名”作为索引名
+func splitIndexTag(tag string, pre string, column string) []string {
+	var names []string
+	for _, v := range strings.Split(tag, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if v == "1" {
+			v = pre + column
+		}
+		names = append(names, v)
+	}
+	return names
+}
+
+//获取主键字段
+func (ms *modelSchema) primaryKeys() []*fieldSchema {
+	var pks []*fieldSchema
+	for _, f := range ms.Fields {
+		if f.IsPK {
+			pks = append(pks, f)
+		}
+	}
+	return pks
+}
+
+//获取软删除标记字段
+func (ms *modelSchema) softDeleteField() *fieldSchema {
+	for _, f := range ms.Fields {
+		if f.SoftDelete {
+			return f
+		}
+	}
+	return nil
+}
+
+//获取乐观锁版本号字段
+func (ms *modelSchema) versionField() *fieldSchema {
+	for _, f := range ms.Fields {
+		if f.IsVersion && f.isInteger() {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+	软删除及乐观锁
+	软删除：struct中标记softdelete:"1"的字段，删除时改为更新此字段，查询（Select/Find/Count）时自动过滤已删除的数据，Unscoped()可取消过滤
+	软删除字段来自当前操作的模型（Model、Find、FindList、Save等设置），仅使用Table("表名")的操作不过滤，需要过滤时先调用Model
+	乐观锁：struct中标记version:"1"的整型字段，Save时附加版本号条件并自增版本号，更新0行时返回ErrStaleObject
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/misgo/aresgo/text"
+)
+
+var (
+	ErrStaleObject = errors.New("数据已被修改，请重新获取后再保存") //乐观锁版本号不一致
+)
+
+//设置当前操作的数据模型（获取表名、主键、软删除等定义，不设置字段值）
+func (m *DbModel) Model(s interface{}) *DbModel {
+	ms := getModelSchema(s)
+	if ms == nil {
+		return m
+	}
+	m.schema = ms
+	if ms.Table != "" && m.TableName == "" {
+		m.TableName = m.realTableName(ms.Table)
+	}
+	for _, f := range ms.primaryKeys() {
+		if _, ok := m.PrimaryKeys[f.Column]; !ok {
+			m.PrimaryKeys[f.Column] = ""
+		}
+	}
+	return m
+}
+
+//取消软删除过滤，查询包含已删除的数据，删除时进行物理删除
+func (m *DbModel) Unscoped() *DbModel {
+	m.unscoped = true
+	return m
+}
+
+//获取当前模型的软删除字段，未设置模型、未定义或已调用Unscoped时返回nil
+func (m *DbModel) softDeleteField() *fieldSchema {
+	if m.unscoped || m.schema == nil {
+		return nil
+	}
+	return m.schema.softDeleteField()
+}
+
+//获取查询条件，模型定义了软删除字段时附加过滤已删除数据的条件
+func (m *DbModel) scopedWhere() string {
+	f := m.softDeleteField()
+	if f == nil {
+		return m.WhereStr
+	}
+	if m.WhereStr == "" {
+		return f.notDeletedCondition()
+	}
+	return Text.SpliceString("(", m.WhereStr, ") AND ", f.notDeletedCondition())
+}
+
+//软删除字段的存储方式
+const (
+	softDeleteNull   = iota //date/datetime：未删除为NULL，删除时写入当前时间
+	softDeleteNumber        //数值：未删除为0，删除时64位整数及int方式的时间写入时间戳，其他写入1
+	softDeleteString        //字符串：未删除为空字符串或NULL，删除时写入当前时间
+)
+
+//获取软删除字段的存储方式，删除值与未删除条件均按此方式生成，保证两者一致
+func (f *fieldSchema) softDeleteKind() int {
+	if f.IsTime {
+		if f.TimeType == "int" {
+			return softDeleteNumber
+		}
+		return softDeleteNull
+	}
+	if f.Kind == reflect.String {
+		return softDeleteString
+	}
+	return softDeleteNumber
+}
+
+//未删除数据的查询条件
+func (f *fieldSchema) notDeletedCondition() string {
+	switch f.softDeleteKind() {
+	case softDeleteNull:
+		return Text.SpliceString(f.Column, " IS NULL")
+	case softDeleteString:
+		return Text.SpliceString("(", f.Column, " IS NULL OR ", f.Column, " = '')")
+	}
+	return Text.SpliceString(f.Column, " = 0")
+}
+
+//软删除时写入的值，按字段类型写入当前时间或删除标记，不会满足notDeletedCondition
+func (f *fieldSchema) deletedValue() interface{} {
+	now := time.Now()
+	switch f.softDeleteKind() {
+	case softDeleteNull:
+		if f.TimeType == "date" {
+			return now.Format("2006-01-02")
+		}
+		return now.Format("2006-01-02 15:04:05")
+	case softDeleteString:
+		return now.Format("2006-01-02 15:04:05")
+	}
+	switch f.Kind {
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return now.Unix()
+	}
+	if f.IsTime { //int方式存储的时间
+		return now.Unix()
+	}
+	return 1 //布尔型、小整型及浮点型只写入删除标记，避免溢出
+}
+
+//获取struct中乐观锁版本号的值
+func versionValue(s interface{}, f *fieldSchema) int64 {
+	fv := reflect.Indirect(reflect.ValueOf(s)).FieldByName(f.Name)
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(fv.Uint())
+	}
+	return 0
+}
+
+//保存成功后将新的版本号回写到struct
+func setVersionValue(s interface{}, f *fieldSchema, version int64) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Ptr {
+		return
+	}
+	fv := rv.Elem().FieldByName(f.Name)
+	if fv.CanSet() {
+		setIntValue(fv, version)
+	}
+}
@@ -0,0 +1,128 @@
+package Db_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+type (
+	softTimeModel struct {
+		Id        int       `field:"id" key:"pk" auto:"1" table:"soft_time"`
+		DeletedAt time.Time `field:"deleted_at" softdelete:"1"`
+	}
+	softIntTimeModel struct {
+		Id        int       `field:"id" key:"pk" auto:"1" table:"soft_int_time"`
+		DeletedAt time.Time `field:"deleted_at" type:"int" softdelete:"1"`
+	}
+	softInt64Model struct {
+		Id        int   `field:"id" key:"pk" auto:"1" table:"soft_int64"`
+		DeletedAt int64 `field:"deleted_at" softdelete:"1"`
+	}
+	softInt8Model struct {
+		Id        int  `field:"id" key:"pk" auto:"1" table:"soft_int8"`
+		IsDeleted int8 `field:"is_deleted" softdelete:"1"`
+	}
+	softBoolModel struct {
+		Id        int  `field:"id" key:"pk" auto:"1" table:"soft_bool"`
+		IsDeleted bool `field:"is_deleted" softdelete:"1"`
+	}
+	softStringModel struct {
+		Id        int    `field:"id" key:"pk" auto:"1" table:"soft_string"`
+		DeletedAt string `field:"deleted_at" softdelete:"1"`
+	}
+)
+
+//软删除写入的值与未删除条件按字段类型保持一致，条件加括号
+func TestSoftDeleteValueMatchesCondition(t *testing.T) {
+	cases := []struct {
+		name  string
+		model interface{}
+		cond  string
+		check func(v interface{}) bool
+	}{
+		{"datetime", &softTimeModel{}, "deleted_at IS NULL", isDatetimeString},
+		{"int time", &softIntTimeModel{}, "deleted_at = 0", isTimestamp},
+		{"int64", &softInt64Model{}, "deleted_at = 0", isTimestamp},
+		{"int8", &softInt8Model{}, "is_deleted = 0", isOne},
+		{"bool", &softBoolModel{}, "is_deleted = 0", isOne},
+		{"string", &softStringModel{}, "(deleted_at IS NULL OR deleted_at = '')", isDatetimeString},
+	}
+	for _, c := range cases {
+		db, mock := dbtest.NewDb()
+		if _, err := db.Model(c.model).Where("a = ? OR b = ?", 1, 2).Delete(); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		r := mock.LastRecord()
+		if r == nil || !strings.HasPrefix(r.SQL, "UPDATE ") {
+			t.Fatalf("%s: 软删除应执行UPDATE，实际为%+v", c.name, r)
+		}
+		if !strings.HasSuffix(r.SQL, "WHERE (a = ? OR b = ?) AND "+c.cond) {
+			t.Errorf("%s: SQL条件错误：%s", c.name, r.SQL)
+		}
+		if len(r.Args) != 3 || !c.check(r.Args[0]) {
+			t.Errorf("%s: 删除值错误：%#v", c.name, r.Args)
+		}
+	}
+}
+
+//Unscoped时物理删除，条件同样加括号
+func TestUnscopedDelete(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	db.Model(&softTimeModel{}).Unscoped().Where("a = ? OR b = ?", 1, 2).Delete()
+	r := mock.LastRecord()
+	if r == nil || r.SQL != "DELETE FROM soft_time  WHERE (a = ? OR b = ?)" {
+		t.Fatalf("物理删除SQL错误：%+v", r)
+	}
+}
+
+//设置模型的查询、统计及删除过滤已删除数据，仅使用Table的操作不受其他操作使用过的模型影响
+func TestSoftDeleteByModel(t *testing.T) {
+	db, mock := dbtest.NewDb()
+
+	db.Model(&softInt64Model{}).Where("name = ?", "a").Count()
+	if r := mock.LastRecord(); !strings.HasSuffix(r.SQL, "WHERE (name = ?) AND deleted_at = 0") {
+		t.Errorf("Count未过滤已删除数据：%s", r.SQL)
+	}
+	db.Model(&softInt64Model{}).Select()
+	if r := mock.LastRecord(); !strings.HasSuffix(r.SQL, "WHERE deleted_at = 0") {
+		t.Errorf("Select未过滤已删除数据：%s", r.SQL)
+	}
+	db.Model(&softInt64Model{}).Where("id = ?", 1).Delete()
+	if r := mock.LastRecord(); !strings.HasPrefix(r.SQL, "UPDATE soft_int64 SET deleted_at = ?") {
+		t.Errorf("Delete未改为软删除：%s", r.SQL)
+	}
+	db.Model(&softInt64Model{}).Unscoped().Select()
+	if r := mock.LastRecord(); strings.Contains(r.SQL, "deleted_at") {
+		t.Errorf("Unscoped不应过滤：%s", r.SQL)
+	}
+	db.Table("soft_int64").Select()
+	if r := mock.LastRecord(); strings.Contains(r.SQL, "WHERE") {
+		t.Errorf("未设置模型的查询不应过滤：%s", r.SQL)
+	}
+	db.Table("soft_int64").Where("id = ?", 1).Delete()
+	if r := mock.LastRecord(); !strings.HasPrefix(r.SQL, "DELETE FROM soft_int64") {
+		t.Errorf("未设置模型的删除不应改为软删除：%s", r.SQL)
+	}
+}
+
+func isDatetimeString(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse("2006-01-02 15:04:05", s)
+	return err == nil
+}
+
+func isTimestamp(v interface{}) bool {
+	n, ok := v.(int64)
+	return ok && n > 1000000000
+}
+
+func isOne(v interface{}) bool {
+	n, ok := v.(int64)
+	return ok && n == 1
+}
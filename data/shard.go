@@ -0,0 +1,419 @@
+/*
+	分库分表：按分片键（mod/hash/range规则）路由到对应的数据库实例及物理表，无分片键的查询在所有分片上执行后合并排序
+	物理表按顺序平均分布在各数据库实例上，如64张表8个库时，t_order_00~07在第1个库，t_order_08~15在第2个库
+	使用方法：
+	s := Db.NewSharding("t_order", dbs...)
+	s.Type, s.TableCount = Db.ShardTypeMod, 64
+	db, err := s.ShardBy(uid)
+	if err == nil {
+		err = db.Where("uid = ?", uid).FindList(&orders)
+	}
+	list, err := s.All().Where("status = ?", 1).OrderBy("create_time DESC").Limit(0, 20).Select()
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ShardTypeMod   = "mod"   //按分片键取模，分片键必须为整数
+	ShardTypeHash  = "hash"  //按分片键的crc32值取模
+	ShardTypeRange = "range" //按分片键的范围
+
+	DefaultShardTableFormat = "%s_%02d" //默认物理表名格式：逻辑表名_序号
+)
+
+type (
+	//分片规则
+	Sharding struct {
+		Table       string       //逻辑表名
+		Type        string       //分片类型：mod/hash/range
+		TableCount  int          //物理表数量，小于1时等于数据库实例数（只分库不分表）
+		TableFormat string       //物理表名格式，默认为DefaultShardTableFormat
+		Ranges      []ShardRange //范围规则（range类型使用）
+
+		dbs []*DbModel
+	}
+
+	//范围规则，分片键在[Min,Max]范围内时使用序号为Index的物理表
+	ShardRange struct {
+		Min   int64
+		Max   int64
+		Index int
+	}
+
+	//跨分片查询（scatter-gather）
+	ShardQuery struct {
+		s      *Sharding
+		column string
+		where  string
+		param  []interface{}
+		order  string
+		offset int
+		rows   int
+	}
+
+	//跨分片查询的排序字段
+	shardOrder struct {
+		column string
+		desc   bool
+	}
+)
+
+//创建分片规则
+//@param table 逻辑表名
+//@param dbs 分片的数据库实例，按顺序分配物理表
+func NewSharding(table string, dbs ...*DbModel) *Sharding {
+	return &Sharding{
+		Table:       table,
+		Type:        ShardTypeMod,
+		TableFormat: DefaultShardTableFormat,
+		dbs:         dbs,
+	}
+}
+
+//物理表数量
+func (s *Sharding) tableCount() int {
+	if s.TableCount < 1 {
+		return len(s.dbs)
+	}
+	return s.TableCount
+}
+
+//物理表名
+func (s *Sharding) tableName(index int) string {
+	if s.TableCount < 1 { //只分库不分表
+		return s.Table
+	}
+	format := s.TableFormat
+	if format == "" {
+		format = DefaultShardTableFormat
+	}
+	return fmt.Sprintf(format, s.Table, index)
+}
+
+//物理表所在的数据库实例序号
+func (s *Sharding) dbIndex(index int) int {
+	return index * len(s.dbs) / s.tableCount()
+}
+
+//根据分片键计算数据库实例序号及物理表名
+func (s *Sharding) Locate(key interface{}) (int, string, error) {
+	if len(s.dbs) < 1 {
+		return 0, "", fmt.Errorf("分片[%s]未设置数据库实例", s.Table)
+	}
+	count := s.tableCount()
+	index := -1
+	switch s.Type {
+	case ShardTypeMod:
+		n, err := shardKeyMod(key, count)
+		if err != nil {
+			return 0, "", err
+		}
+		index = n
+	case ShardTypeHash:
+		index = int(crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v", key))) % uint32(count))
+	case ShardTypeRange:
+		n, err := shardKeyInt(key)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, r := range s.Ranges {
+			if n >= r.Min && n <= r.Max {
+				index = r.Index
+				break
+			}
+		}
+		if index < 0 || index >= count {
+			return 0, "", fmt.Errorf("分片键[%d]不在分片[%s]的范围内", n, s.Table)
+		}
+	default:
+		return 0, "", fmt.Errorf("不支持的分片类型[%s]", s.Type)
+	}
+	return s.dbIndex(index), s.tableName(index), nil
+}
+
+//根据分片键获取对应的数据库对象（独立的Session），并设置为对应的物理表，分片键无法路由时返回错误
+func (s *Sharding) ShardBy(key interface{}) (*DbModel, error) {
+	i, table, err := s.Locate(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.dbs[i].Session().Table(table), nil
+}
+
+//所有物理表名，按序号排列
+func (s *Sharding) Tables() []string {
+	count := s.tableCount()
+	tables := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		tables = append(tables, s.tableName(i))
+	}
+	return tables
+}
+
+//在所有分片上执行查询（无分片键的查询）
+func (s *Sharding) All() *ShardQuery {
+	return &ShardQuery{s: s}
+}
+
+//设定选择的字段，跨分片排序时必须包含排序字段
+func (q *ShardQuery) Field(fields ...string) *ShardQuery {
+	q.column = strings.Join(fields, ",")
+	return q
+}
+
+//查询条件
+func (q *ShardQuery) Where(queryString string, args ...interface{}) *ShardQuery {
+	if strings.Count(queryString, "?") != len(args) {
+		err := fmt.Sprintf("查询条件[%s]与参数个数不对应", queryString)
+		panic(err)
+	}
+	q.where = queryString
+	q.param = args
+	return q
+}
+
+//排序，合并各分片结果后重新排序（数字按数值比较，其他按字符串比较）
+func (q *ShardQuery) OrderBy(order ...string) *ShardQuery {
+	q.order = strings.Join(order, ",")
+	return q
+}
+
+//分页，每个分片查询前start+size条，合并排序后再分页
+func (q *ShardQuery) Limit(start int, size int) *ShardQuery {
+	q.offset = start
+	q.rows = size
+	return q
+}
+
+//在所有分片上查询并合并结果
+func (q *ShardQuery) Select() ([]map[string]string, error) {
+	return q.gather(nil)
+}
+
+//在所有分片上查询并映射为struct列表
+//@param structList struct切片的指针
+func (q *ShardQuery) FindList(structList interface{}) error {
+	rv := reflect.ValueOf(structList)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("获取的数据类型必须为切片指针")
+	}
+	model := reflect.New(rv.Elem().Type().Elem()).Interface()
+	rows, err := q.gather(model)
+	if err != nil {
+		return err
+	}
+	m := q.s.dbs[0].Session()
+	m.ConvertModelToMap(model)
+	list, err := m.mapsToList(rv.Elem().Type(), rows)
+	if err != nil {
+		return err
+	}
+	rv.Elem().Set(list)
+	return nil
+}
+
+//统计所有分片的记录总数
+func (q *ShardQuery) Count() (int, error) {
+	var mu sync.Mutex
+	var total int
+	err := q.each(func(m *DbModel, table string) error {
+		m.Table(table)
+		if q.where != "" {
+			m.Where(q.where, q.param...)
+		}
+		count, err := m.count()
+		mu.Lock()
+		total += count
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
+
+//在所有分片上查询，合并后排序分页
+//@param model struct模型（可选），用于设置查询字段及软删除条件
+func (q *ShardQuery) gather(model interface{}) ([]map[string]string, error) {
+	var mu sync.Mutex
+	list := make([]map[string]string, 0)
+	err := q.each(func(m *DbModel, table string) error {
+		if model != nil {
+			m.ConvertModelToMap(model)
+		}
+		m.Table(table)
+		if q.column != "" {
+			m.Column = q.column
+		}
+		if q.where != "" {
+			m.Where(q.where, q.param...)
+		}
+		m.Order = q.order
+		if q.rows > 0 {
+			m.Limit(0, q.offset+q.rows)
+		}
+		rows, err := m.selectRows()
+		mu.Lock()
+		list = append(list, rows...)
+		mu.Unlock()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if q.order != "" {
+		orders := parseShardOrder(q.order)
+		sort.SliceStable(list, func(i, j int) bool {
+			return lessShardRow(list[i], list[j], orders)
+		})
+	}
+	if q.offset > 0 {
+		if q.offset >= len(list) {
+			return list[:0], nil
+		}
+		list = list[q.offset:]
+	}
+	if q.rows > 0 && len(list) > q.rows {
+		list = list[:q.rows]
+	}
+	return list, nil
+}
+
+//遍历所有物理表，同一数据库实例上的表顺序执行，不同实例并发执行，返回第一个错误
+//每张表使用独立的Session，不修改其他请求共用的数据库对象
+func (q *ShardQuery) each(fn func(m *DbModel, table string) error) error {
+	s := q.s
+	if len(s.dbs) < 1 {
+		return fmt.Errorf("分片[%s]未设置数据库实例", s.Table)
+	}
+	groups := make(map[*DbModel][]string)
+	var dbs []*DbModel
+	for i, table := range s.Tables() {
+		m := s.dbs[s.dbIndex(i)]
+		if _, ok := groups[m]; !ok {
+			dbs = append(dbs, m)
+		}
+		groups[m] = append(groups[m], table)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(dbs))
+	for i, m := range dbs {
+		wg.Add(1)
+		go func(i int, m *DbModel) {
+			defer wg.Done()
+			for _, table := range groups[m] {
+				if err := fn(m.Session(), table); err != nil {
+					errs[i] = fmt.Errorf("分片[%s]查询失败:%s", table, err.Error())
+					return
+				}
+			}
+		}(i, m)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//解析排序字符串，如“create_time DESC,id”
+func parseShardOrder(order string) []shardOrder {
+	var orders []shardOrder
+	for _, item := range strings.Split(order, ",") {
+		fields := strings.Fields(item)
+		if len(fields) < 1 {
+			continue
+		}
+		o := shardOrder{column: fields[0]}
+		if idx := strings.LastIndex(o.column, "."); idx >= 0 { //去掉表名
+			o.column = o.column[idx+1:]
+		}
+		o.column = strings.Trim(o.column, "`")
+		if len(fields) > 1 && strings.ToUpper(fields[1]) == "DESC" {
+			o.desc = true
+		}
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+//按排序字段比较两行数据
+func lessShardRow(a map[string]string, b map[string]string, orders []shardOrder) bool {
+	for _, o := range orders {
+		va, vb := a[o.column], b[o.column]
+		if va == vb {
+			continue
+		}
+		var less bool
+		fa, errA := strconv.ParseFloat(va, 64)
+		fb, errB := strconv.ParseFloat(vb, 64)
+		if errA == nil && errB == nil {
+			if fa == fb {
+				continue
+			}
+			less = fa < fb
+		} else {
+			less = va < vb
+		}
+		if o.desc {
+			return !less
+		}
+		return less
+	}
+	return false
+}
+
+//分片键对物理表数量取模，无符号整数按无符号取模，有符号整数取模后取绝对值
+func shardKeyMod(key interface{}, count int) (int, error) {
+	rv := reflect.ValueOf(key)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(rv.Uint() % uint64(count)), nil
+	case reflect.String:
+		if u, err := strconv.ParseUint(rv.String(), 10, 64); err == nil {
+			return int(u % uint64(count)), nil
+		}
+	}
+	n, err := shardKeyInt(key)
+	if err != nil {
+		return 0, err
+	}
+	n %= int64(count) //先取模再取绝对值，避免math.MinInt64取反溢出
+	if n < 0 {
+		n = -n
+	}
+	return int(n), nil
+}
+
+//将分片键转换为整数，超出int64范围的无符号整数返回错误
+func shardKeyInt(key interface{}) (int64, error) {
+	rv := reflect.ValueOf(key)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u), nil
+		}
+	case reflect.String:
+		n, err := strconv.ParseInt(rv.String(), 10, 64)
+		if err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("分片键[%v]必须为整数", key)
+}
@@ -0,0 +1,44 @@
+package Db_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/misgo/aresgo/data"
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+//跨分片查询使用独立的Session，不修改共用的数据库对象，可与其他请求并发执行
+func TestShardQueryUsesSession(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`FROM t_order_0[0-3] WHERE status = \?`).Times(0).WillReturnRows([]string{"id"}, []interface{}{1})
+	s := Db.NewSharding("t_order", db)
+	s.TableCount = 4
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			list, err := s.All().Where("status = ?", 1).Select()
+			if err != nil || len(list) != 4 {
+				t.Errorf("跨分片查询结果错误：%v %v", list, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			db.Session().Table("user").Where("id = ?", 1).Select()
+		}()
+	}
+	wg.Wait()
+	if n, err := s.All().Where("status = ?", 1).Count(); err != nil || n != 4 {
+		t.Errorf("统计结果错误：%d %v", n, err)
+	}
+	if db.TableName != "" || db.WhereStr != "" {
+		t.Errorf("不应修改共用的数据库对象：%q %q", db.TableName, db.WhereStr)
+	}
+	shard, err := s.ShardBy(5)
+	if err != nil || shard == db || shard.TableName != "t_order_01" || db.TableName != "" {
+		t.Errorf("ShardBy应返回独立的Session：%v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package Db
+
+import (
+	"math"
+	"testing"
+)
+
+//按分片键路由到数据库实例及物理表
+func TestShardingLocate(t *testing.T) {
+	dbs := []*DbModel{{}, {}}
+	mod := NewSharding("t_order", dbs...)
+	mod.TableCount = 8
+	rng := NewSharding("t_log", dbs...)
+	rng.Type, rng.TableCount = ShardTypeRange, 2
+	rng.Ranges = []ShardRange{{Min: 0, Max: 99, Index: 0}, {Min: 100, Max: 199, Index: 1}}
+	dbOnly := NewSharding("t_user", dbs...)
+
+	cases := []struct {
+		name  string
+		s     *Sharding
+		key   interface{}
+		db    int
+		table string
+	}{
+		{"mod", mod, 13, 1, "t_order_05"},
+		{"mod string", mod, "3", 0, "t_order_03"},
+		{"mod negative", mod, int64(-13), 1, "t_order_05"},
+		{"mod MinInt64", mod, int64(math.MinInt64), 0, "t_order_00"},
+		{"mod uint64", mod, uint64(9), 0, "t_order_01"},
+		{"mod MaxUint64", mod, uint64(math.MaxUint64), 1, "t_order_07"},
+		{"mod MaxUint64 string", mod, "18446744073709551615", 1, "t_order_07"},
+		{"range", rng, 150, 1, "t_log_01"},
+		{"db only", dbOnly, 3, 1, "t_user"},
+	}
+	for _, c := range cases {
+		db, table, err := c.s.Locate(c.key)
+		if err != nil || db != c.db || table != c.table {
+			t.Errorf("%s: 路由结果错误：%d %s %v", c.name, db, table, err)
+		}
+	}
+
+	hash := NewSharding("t_hash", dbs...)
+	hash.Type, hash.TableCount = ShardTypeHash, 4
+	db1, table1, err1 := hash.Locate("abc")
+	db2, table2, err2 := hash.Locate("abc")
+	if err1 != nil || err2 != nil || db1 != db2 || table1 != table2 {
+		t.Errorf("hash路由结果不稳定：%d %s %d %s", db1, table1, db2, table2)
+	}
+}
+
+//无法路由时返回错误
+func TestShardingLocateErrors(t *testing.T) {
+	rng := NewSharding("t_log", &DbModel{})
+	rng.Type, rng.TableCount = ShardTypeRange, 2
+	rng.Ranges = []ShardRange{{Min: 0, Max: 99, Index: 0}}
+	bad := NewSharding("t_bad", &DbModel{})
+	bad.Type = "unknown"
+
+	cases := []struct {
+		name string
+		s    *Sharding
+		key  interface{}
+	}{
+		{"no db", NewSharding("t_order"), 1},
+		{"not int", NewSharding("t_order", &DbModel{}), "abc"},
+		{"out of range", rng, 100},
+		{"uint64 out of range", rng, uint64(math.MaxUint64)},
+		{"unknown type", bad, 1},
+	}
+	for _, c := range cases {
+		if _, _, err := c.s.Locate(c.key); err == nil {
+			t.Errorf("%s: 应返回错误", c.name)
+		}
+		if db, err := c.s.ShardBy(c.key); err == nil || db != nil {
+			t.Errorf("%s: ShardBy应返回错误", c.name)
+		}
+	}
+}
+
+//物理表按顺序平均分布在各数据库实例上
+func TestShardingDbIndex(t *testing.T) {
+	s := NewSharding("t_order", make([]*DbModel, 8)...)
+	s.TableCount = 64
+	for i, want := range map[int]int{0: 0, 7: 0, 8: 1, 63: 7} {
+		if got := s.dbIndex(i); got != want {
+			t.Errorf("表%d应在第%d个库，实际为%d", i, want, got)
+		}
+	}
+}
+
+//跨分片合并时按排序字段比较，数字按数值比较
+func TestLessShardRow(t *testing.T) {
+	orders := parseShardOrder("t.`score` DESC, id")
+	if len(orders) != 2 || orders[0].column != "score" || !orders[0].desc || orders[1].column != "id" || orders[1].desc {
+		t.Fatalf("排序解析错误：%+v", orders)
+	}
+	cases := []struct {
+		a, b map[string]string
+		want bool
+	}{
+		{map[string]string{"score": "10", "id": "1"}, map[string]string{"score": "9", "id": "2"}, true},
+		{map[string]string{"score": "9", "id": "1"}, map[string]string{"score": "10", "id": "2"}, false},
+		{map[string]string{"score": "5", "id": "1"}, map[string]string{"score": "5.0", "id": "2"}, true},
+		{map[string]string{"score": "5", "id": "b"}, map[string]string{"score": "5", "id": "a"}, false},
+	}
+	for i, c := range cases {
+		if got := lessShardRow(c.a, c.b, orders); got != c.want {
+			t.Errorf("第%d组比较结果错误：%v", i, got)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+/*
+	流式查询及分批查询，用于导出等大数据量场景，避免将全部结果集加载到内存
+	使用方法：
+	err := D("dev").Table("user").Where("status = ?", 1).Each(func(row map[string]string) error {
+		return nil
+	})
+	err := D("dev").Table("user").SetPK("id").Chunk(1000, func(rows []map[string]string) error {
+		return nil
+	})
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
+)
+
+//查询条件快照，用于需要多次执行同一查询条件的操作（分批查询、分页等）
+type queryState struct {
+	tableName   string
+	column      string
+	where       string
+	param       []interface{}
+	groupBy     string
+	having      string
+	order       string
+	offset      int
+	rowsNum     int
+	primaryKeys map[string]interface{}
+	schema      *modelSchema
+	unscoped    bool
+	counter     *QueryCounter
+	useCache    bool
+	cacheTTL    time.Duration
+	cacheKey    string
+}
+
+//保存当前的查询条件
+func (m *DbModel) saveState() *queryState {
+	st := &queryState{
+		tableName:   m.TableName,
+		column:      m.Column,
+		where:       m.WhereStr,
+		param:       append([]interface{}{}, m.Param...),
+		groupBy:     m.GroupByStr,
+		having:      m.HavingStr,
+		order:       m.Order,
+		offset:      m.Offset,
+		rowsNum:     m.RowsNum,
+		primaryKeys: make(map[string]interface{}, len(m.PrimaryKeys)),
+		schema:      m.schema,
+		unscoped:    m.unscoped,
+		counter:     m.counter,
+		useCache:    m.useCache,
+		cacheTTL:    m.cacheTTL,
+		cacheKey:    m.cacheKey,
+	}
+	for k, v := range m.PrimaryKeys {
+		st.primaryKeys[k] = v
+	}
+	return st
+}
+
+//恢复保存的查询条件
+func (m *DbModel) restoreState(st *queryState) {
+	m.TableName = st.tableName
+	m.Column = st.column
+	m.WhereStr = st.where
+	m.Param = append([]interface{}{}, st.param...)
+	m.GroupByStr = st.groupBy
+	m.HavingStr = st.having
+	m.Order = st.order
+	m.Offset = st.offset
+	m.RowsNum = st.rowsNum
+	m.PrimaryKeys = make(map[string]interface{}, len(st.primaryKeys))
+	for k, v := range st.primaryKeys {
+		m.PrimaryKeys[k] = v
+	}
+	m.schema = st.schema
+	m.unscoped = st.unscoped
+	m.counter = st.counter
+	m.useCache = st.useCache
+	m.cacheTTL = st.cacheTTL
+	m.cacheKey = st.cacheKey
+}
+
+//按当前查询条件执行查询并返回*sql.Rows，调用方必须在使用完成后调用rows.Close()
+func (m *DbModel) Rows() (*sql.Rows, error) {
+	defer m.ResetDbModel()
+	if m.dbReader == nil {
+		return nil, errors.New("数据库实例未初始化")
+	}
+	sqlstr := m.buildSelectSql()
+	//SQL调试
+	if frame.Debug {
+		Text.Log("debug").Debug(sqlstr)
+	}
+	e := m.traceBefore(MethodSelect, sqlstr, m.Param, false)
+	rows, err := m.dbReader.Query(sqlstr, m.Param...)
+	m.traceAfter(e, -1, err)
+	return rows, err
+}
+
+//流式遍历查询结果，每读取一行调用一次fn，fn返回错误时停止遍历并返回此错误
+func (m *DbModel) Each(fn func(row map[string]string) error) error {
+	rows, err := m.Rows()
+	if err != nil {
+		return err
+	}
+	return eachRow(rows, fn)
+}
+
+//流式遍历查询结果并映射到struct，每读取一行将数据写入i后调用一次fn
+//@param i struct对象指针，每行数据都会覆盖此对象的值
+func (m *DbModel) FindEach(i interface{}, fn func() error) error {
+	rv := reflect.ValueOf(i)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		m.ResetDbModel()
+		return errors.New("获取的数据类型必须为struct指针")
+	}
+	m.ConvertModelToMap(i) //将字段结构转换map
+	zero := reflect.Zero(rv.Elem().Type())
+	return m.Each(func(row map[string]string) error {
+		rv.Elem().Set(zero)
+		if err := m.ConvertMapToModel(row, i); err != nil {
+			return err
+		}
+		if err := afterFind(i); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+//按主键顺序分批查询（keyset分页，不使用offset），每批数据调用一次fn
+//需要通过SetPK或struct模型设置唯一主键，查询字段中必须包含主键
+//@param size 每批的数量
+func (m *DbModel) Chunk(size int, fn func(rows []map[string]string) error) error {
+	defer m.ResetDbModel()
+	if size < 1 {
+		return errors.New("每批数量必须大于0")
+	}
+	if len(m.PrimaryKeys) != 1 {
+		return errors.New("分批查询必须设置唯一的主键")
+	}
+	if m.dbReader == nil {
+		return errors.New("数据库实例未初始化")
+	}
+	var pk string
+	for k := range m.PrimaryKeys {
+		pk = k
+	}
+	m.useCache = false //分批查询用于遍历全部数据，不缓存
+	st := m.saveState()
+	var last interface{} = nil //上一批最后一条数据的主键值
+	for {
+		m.restoreState(st)
+		if last != nil {
+			if m.WhereStr == "" {
+				m.WhereStr = Text.SpliceString(pk, " > ?")
+			} else {
+				m.WhereStr = Text.SpliceString("(", m.WhereStr, ") AND ", pk, " > ?")
+			}
+			m.Param = append(m.Param, last)
+		}
+		m.Order = Text.SpliceString(pk, " ASC")
+		m.Offset = 0
+		m.RowsNum = size
+		list, err := m.selectRows()
+		if err != nil {
+			return err
+		}
+		if len(list) < 1 {
+			return nil
+		}
+		if err = fn(list); err != nil {
+			return err
+		}
+		if len(list) < size {
+			return nil
+		}
+		v, ok := list[len(list)-1][pk]
+		if !ok {
+			return errors.New("分批查询的字段中必须包含主键")
+		}
+		last = v
+	}
+}
+
+//按主键顺序分批查询并映射为struct列表，每批数据写入structList后调用一次fn
+//@param structList struct切片的指针，每批查询前会清空
+func (m *DbModel) FindChunk(structList interface{}, size int, fn func() error) error {
+	rv := reflect.ValueOf(structList)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		m.ResetDbModel()
+		return errors.New("获取的数据类型必须为切片指针")
+	}
+	m.ConvertModelToMap(reflect.New(rv.Elem().Type().Elem()).Interface()) //将字段结构转换map
+	return m.Chunk(size, func(rows []map[string]string) error {
+		list, err := m.mapsToList(rv.Elem().Type(), rows)
+		if err != nil {
+			return err
+		}
+		rv.Elem().Set(list)
+		return fn()
+	})
+}
+
+//将查询出的数据列表映射为struct切片
+//@param sliceType struct切片的类型
+func (m *DbModel) mapsToList(sliceType reflect.Type, rows []map[string]string) (reflect.Value, error) {
+	rt := sliceType.Elem()
+	list := reflect.MakeSlice(sliceType, 0, len(rows))
+	for _, row := range rows {
+		item := reflect.New(rt)
+		if err := m.ConvertMapToModel(row, item.Interface()); err != nil {
+			return list, err
+		}
+		if err := afterFind(item.Interface()); err != nil {
+			return list, err
+		}
+		list = reflect.Append(list, item.Elem())
+	}
+	return list, nil
+}
+
+//遍历*sql.Rows，将每行数据转换为map后调用fn，遍历结束后关闭rows
+func eachRow(rows *sql.Rows, fn func(row map[string]string) error) error {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	scanArgs := make([]interface{}, len(values))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(scanArgs...); err != nil {
+			return err
+		}
+		vmap := make(map[string]string, len(columns))
+		for i, col := range values {
+			if col == nil {
+				vmap[columns[i]] = "NULL"
+			} else {
+				vmap[columns[i]] = string(col)
+			}
+		}
+		if err = fn(vmap); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
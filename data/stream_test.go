@@ -0,0 +1,72 @@
+package Db_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+//逐行遍历查询结果，fn返回错误时停止遍历
+func TestEach(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`FROM user`).Times(2).WillReturnRows([]string{"id", "name"},
+		[]interface{}{1, "a"}, []interface{}{2, "b"}, []interface{}{3, "c"})
+	var names []string
+	err := db.Table("user").Each(func(row map[string]string) error {
+		names = append(names, row["name"])
+		return nil
+	})
+	if err != nil || len(names) != 3 || names[2] != "c" {
+		t.Fatalf("遍历结果错误：%v %v", names, err)
+	}
+	stop := errors.New("stop")
+	n := 0
+	err = db.Table("user").Each(func(row map[string]string) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("fn返回错误时应停止遍历并返回此错误：%d %v", n, err)
+	}
+}
+
+//按主键分批查询，后续批次以上一批最后的主键为条件，不足一批时结束
+func TestChunk(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`^SELECT \* FROM user WHERE status = \? ORDER BY id ASC LIMIT 0,2$`).
+		WillReturnRows([]string{"id"}, []interface{}{1}, []interface{}{2})
+	mock.ExpectQuery(`WHERE \(status = \?\) AND id > \? ORDER BY id ASC LIMIT 0,2$`).WithArgs(1, "2").
+		WillReturnRows([]string{"id"}, []interface{}{5})
+	var ids []string
+	err := db.Table("user").SetPK("id").Where("status = ?", 1).Chunk(2, func(rows []map[string]string) error {
+		for _, row := range rows {
+			ids = append(ids, row["id"])
+		}
+		return nil
+	})
+	if err != nil || len(ids) != 3 || ids[2] != "5" {
+		t.Fatalf("分批查询结果错误：%v %v", ids, err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//每批数量小于1或未设置主键时返回错误
+func TestChunkErrors(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	fn := func([]map[string]string) error { return nil }
+	if err := db.Table("user").SetPK("id").Chunk(0, fn); err == nil {
+		t.Error("每批数量小于1时应返回错误")
+	}
+	if err := db.Table("user").Chunk(10, fn); err == nil {
+		t.Error("未设置主键时应返回错误")
+	}
+	if err := db.Table("user").SetPK("id", "uid").Chunk(10, fn); err == nil {
+		t.Error("联合主键应返回错误")
+	}
+	if n := len(mock.Records()); n != 0 {
+		t.Errorf("参数错误时不应执行查询，实际执行%d次", n)
+	}
+}
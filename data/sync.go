@@ -0,0 +1,343 @@
+/*
+	数据表结构同步，根据struct定义创建数据表、添加缺失的字段和索引
+	为保证数据安全，同步时不会删除或修改已存在的字段
+	使用方法：
+	D("dev").Sync(&UserInfo{}, &GroupInfo{}) //同步到数据库
+	sqls, err := D("dev").SyncSQL(&UserInfo{}) //只生成SQL语句不执行（dry-run）
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
+)
+
+//同步struct结构到数据库：创建不存在的表，添加缺失的字段及索引
+//@param models struct对象指针，可传多个
+func (m *DbModel) Sync(models ...interface{}) error {
+	sqls, err := m.SyncSQL(models...)
+	if err != nil {
+		return err
+	}
+	for _, sqlstr := range sqls {
+		//SQL调试
+		if frame.Debug {
+			Text.Log("debug").Debug(sqlstr)
+		}
+		e := m.traceBefore("DDL", sqlstr, nil, true)
+		_, err = m.dbWriter.Exec(sqlstr)
+		m.traceAfter(e, 0, err)
+		if err != nil {
+			Text.Log("db_error").Error(fmt.Sprintf("sync error:%s;sql:%s", err.Error(), sqlstr))
+			return err
+		}
+	}
+	return nil
+}
+
+//生成同步struct结构所需的SQL语句，但不执行（dry-run），用于上线前审核
+//@param models struct对象指针，可传多个
+func (m *DbModel) SyncSQL(models ...interface{}) ([]string, error) {
+	if m.dbWriter == nil {
+		return nil, errors.New("数据库实例未初始化")
+	}
+	var sqls []string
+	for _, s := range models {
+		ms := getModelSchema(s)
+		if ms == nil {
+			return nil, errors.New("同步的数据类型必须为struct")
+		}
+		if ms.Table == "" {
+			return nil, fmt.Errorf("[%v]未定义table标签", reflect.TypeOf(s))
+		}
+		tbname := m.realTableName(ms.Table)
+		tables, err := m.queryStrings("SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", tbname)
+		if err != nil {
+			return nil, err
+		}
+		if len(tables) < 1 { //表不存在，创建新表
+			sqlstr, err := m.createTableSql(tbname, ms)
+			if err != nil {
+				return nil, err
+			}
+			sqls = append(sqls, sqlstr)
+			continue
+		}
+		alters, err := m.alterTableSql(tbname, ms)
+		if err != nil {
+			return nil, err
+		}
+		sqls = append(sqls, alters...)
+	}
+	return sqls, nil
+}
+
+//生成建表语句
+func (m *DbModel) createTableSql(tbname string, ms *modelSchema) (string, error) {
+	var items []string
+	for _, f := range ms.Fields {
+		def, err := f.columnDefinition()
+		if err != nil {
+			return "", err
+		}
+		items = append(items, def)
+	}
+	if pks := ms.primaryKeys(); len(pks) > 0 {
+		var cols []string
+		for _, f := range pks {
+			cols = append(cols, quoteName(f.Column))
+		}
+		items = append(items, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(cols, ", ")))
+	}
+	for _, idx := range ms.indexes() {
+		items = append(items, idx.definition())
+	}
+	sb := Text.NewString("CREATE TABLE ", quoteName(tbname), " (\n  ")
+	sb.Append(strings.Join(items, ",\n  "))
+	sb.Append("\n) ENGINE=InnoDB")
+	if m.charset != "" {
+		sb.Append(" DEFAULT CHARSET=")
+		sb.Append(m.charset)
+	}
+	return sb.ToString(), nil
+}
+
+//生成修改表结构的语句，只添加缺失的字段及索引
+func (m *DbModel) alterTableSql(tbname string, ms *modelSchema) ([]string, error) {
+	columns, err := m.queryStrings("SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", tbname)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := m.queryStrings("SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", tbname)
+	if err != nil {
+		return nil, err
+	}
+	existColumns := toLowerSet(columns)
+	existIndexes := toLowerSet(indexes)
+
+	var sqls []string
+	var prev string //新字段添加到前一个字段之后，保持与struct定义的顺序一致
+	for _, f := range ms.Fields {
+		if _, ok := existColumns[strings.ToLower(f.Column)]; !ok {
+			def, err := f.columnDefinition()
+			if err != nil {
+				return nil, err
+			}
+			sqlstr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteName(tbname), def)
+			if prev != "" {
+				sqlstr = Text.SpliceString(sqlstr, " AFTER ", quoteName(prev))
+			} else {
+				sqlstr = Text.SpliceString(sqlstr, " FIRST")
+			}
+			sqls = append(sqls, sqlstr)
+		}
+		prev = f.Column
+	}
+	for _, idx := range ms.indexes() {
+		if _, ok := existIndexes[strings.ToLower(idx.Name)]; !ok {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD %s", quoteName(tbname), idx.definition()))
+		}
+	}
+	return sqls, nil
+}
+
+//查询单列字符串结果（结构同步使用，不改变当前DbModel的查询条件）
+func (m *DbModel) queryStrings(sqlstr string, args ...interface{}) ([]string, error) {
+	rows, err := m.dbWriter.Query(sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []string
+	for rows.Next() {
+		var s string
+		if err = rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+//获取带表前缀的真实表名
+func (m *DbModel) realTableName(tbname string) string {
+	if m.EnableTbPre {
+		return fmt.Sprintf("%s%s", m.TbPre, tbname)
+	}
+	return tbname
+}
+
+//索引定义
+type indexSchema struct {
+	Name     string
+	IsUnique bool
+	Columns  []string
+}
+
+//获取模型中定义的索引，组合索引按字段定义顺序排列
+func (ms *modelSchema) indexes() []*indexSchema {
+	var list []*indexSchema
+	idxMap := make(map[string]*indexSchema)
+	add := func(name string, unique bool, column string) {
+		idx, ok := idxMap[name]
+		if !ok {
+			idx = &indexSchema{Name: name, IsUnique: unique}
+			idxMap[name] = idx
+			list = append(list, idx)
+		}
+		idx.Columns = append(idx.Columns, column)
+	}
+	for _, f := range ms.Fields {
+		for _, name := range f.Indexes {
+			add(name, false, f.Column)
+		}
+		for _, name := range f.Uniques {
+			add(name, true, f.Column)
+		}
+	}
+	return list
+}
+
+//索引的定义语句
+func (idx *indexSchema) definition() string {
+	var cols []string
+	for _, c := range idx.Columns {
+		cols = append(cols, quoteName(c))
+	}
+	keyType := "INDEX"
+	if idx.IsUnique {
+		keyType = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("%s %s (%s)", keyType, quoteName(idx.Name), strings.Join(cols, ", "))
+}
+
+//字段的定义语句
+func (f *fieldSchema) columnDefinition() (string, error) {
+	colType := f.columnType()
+	if colType == "" {
+		return "", fmt.Errorf("字段[%s]的类型[%v]不支持同步", f.Name, f.Kind)
+	}
+	nullable := f.IsTime && f.TimeType != "int" //时间类型允许为空，零值时间不会写入数据库
+	if f.HasDefault && !nullable && strings.ToUpper(f.Default) == "NULL" {
+		return "", fmt.Errorf("字段[%s]不允许为NULL，不能设置默认值NULL", f.Name)
+	}
+	sb := Text.NewString(quoteName(f.Column), " ", colType)
+	if nullable {
+		sb.Append(" NULL")
+	} else {
+		sb.Append(" NOT NULL")
+	}
+	if f.IsAuto && f.IsPK && f.isInteger() {
+		sb.Append(" AUTO_INCREMENT")
+		return sb.ToString(), nil
+	}
+	if f.HasDefault {
+		sb.Append(" DEFAULT ")
+		sb.Append(f.defaultValue())
+	} else if f.IsTime {
+		if f.TimeType == "int" {
+			sb.Append(" DEFAULT 0")
+		}
+	} else if strings.HasPrefix(colType, "VARCHAR") {
+		sb.Append(" DEFAULT ''")
+	} else if !strings.HasSuffix(colType, "TEXT") {
+		sb.Append(" DEFAULT 0")
+	}
+	return sb.ToString(), nil
+}
+
+//根据struct字段类型获取数据库字段类型
+func (f *fieldSchema) columnType() string {
+	if f.IsTime {
+		switch f.TimeType {
+		case "date":
+			return "DATE"
+		case "int":
+			return "BIGINT"
+		default:
+			return "DATETIME"
+		}
+	}
+	switch f.Kind {
+	case reflect.String:
+		if f.Size <= 0 {
+			return "VARCHAR(255)"
+		} else if f.Size <= 21845 {
+			return fmt.Sprintf("VARCHAR(%d)", f.Size)
+		} else if f.Size <= 65535 {
+			return "TEXT"
+		}
+		return "LONGTEXT"
+	case reflect.Bool:
+		return "TINYINT(1)"
+	case reflect.Int8:
+		return "TINYINT"
+	case reflect.Int16:
+		return "SMALLINT"
+	case reflect.Int32:
+		return "INT"
+	case reflect.Int, reflect.Int64:
+		return "BIGINT"
+	case reflect.Uint8:
+		return "TINYINT UNSIGNED"
+	case reflect.Uint16:
+		return "SMALLINT UNSIGNED"
+	case reflect.Uint32:
+		return "INT UNSIGNED"
+	case reflect.Uint, reflect.Uint64:
+		return "BIGINT UNSIGNED"
+	case reflect.Float32:
+		return "FLOAT"
+	case reflect.Float64:
+		return "DOUBLE"
+	}
+	return ""
+}
+
+//字段默认值，数值及时间关键字不加引号，字符串转义反斜杠及单引号
+func (f *fieldSchema) defaultValue() string {
+	upper := strings.ToUpper(f.Default)
+	if upper == "NULL" || upper == "CURRENT_TIMESTAMP" {
+		return upper
+	}
+	if f.isInteger() || f.Kind == reflect.Float32 || f.Kind == reflect.Float64 || f.Kind == reflect.Bool || f.IsTime && f.TimeType == "int" {
+		if _, err := strconv.ParseFloat(f.Default, 64); err == nil {
+			return f.Default
+		}
+	}
+	value := strings.Replace(f.Default, `\`, `\\`, -1)
+	return Text.SpliceString("'", strings.Replace(value, "'", "''", -1), "'")
+}
+
+//是否为整数类型
+func (f *fieldSchema) isInteger() bool {
+	switch f.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+//数据库标识符加反引号
+func quoteName(name string) string {
+	return Text.SpliceString("`", strings.Replace(name, "`", "``", -1), "`")
+}
+
+//转换为小写的集合
+func toLowerSet(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		set[strings.ToLower(v)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,125 @@
+package Db_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/data"
+	"github.com/misgo/aresgo/data/dbtest"
+)
+
+type syncUser struct {
+	Id        int64     `field:"id" key:"pk" auto:"1" table:"sync_user"`
+	Name      string    `field:"name" size:"64" index:"1" default:"it's"`
+	Email     string    `field:"email" unique:"uk_email,uk_name_email"`
+	Nick      string    `field:"nick" unique:"uk_name_email"`
+	Bio       string    `field:"bio" size:"70000"`
+	Score     float64   `field:"score" default:"1.5"`
+	Status    int8      `field:"status"`
+	Active    bool      `field:"active"`
+	Birthday  time.Time `field:"birthday" type:"date"`
+	CreatedAt time.Time `field:"created_at" type:"int"`
+	UpdatedAt time.Time `field:"updated_at" default:"CURRENT_TIMESTAMP"`
+	Group     string    //没有field标签，不同步
+}
+
+//表不存在时生成建表语句
+func TestSyncSQLCreateTable(t *testing.T) {
+	db, _ := dbtest.NewDb(&Db.DbSettings{Charset: "utf8mb4"})
+	sqls, err := db.SyncSQL(&syncUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE `sync_user` (\n" +
+		"  `id` BIGINT NOT NULL AUTO_INCREMENT,\n" +
+		"  `name` VARCHAR(64) NOT NULL DEFAULT 'it''s',\n" +
+		"  `email` VARCHAR(255) NOT NULL DEFAULT '',\n" +
+		"  `nick` VARCHAR(255) NOT NULL DEFAULT '',\n" +
+		"  `bio` LONGTEXT NOT NULL,\n" +
+		"  `score` DOUBLE NOT NULL DEFAULT 1.5,\n" +
+		"  `status` TINYINT NOT NULL DEFAULT 0,\n" +
+		"  `active` TINYINT(1) NOT NULL DEFAULT 0,\n" +
+		"  `birthday` DATE NULL,\n" +
+		"  `created_at` BIGINT NOT NULL DEFAULT 0,\n" +
+		"  `updated_at` DATETIME NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  INDEX `idx_name` (`name`),\n" +
+		"  UNIQUE INDEX `uk_email` (`email`),\n" +
+		"  UNIQUE INDEX `uk_name_email` (`email`, `nick`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	if len(sqls) != 1 || sqls[0] != want {
+		t.Fatalf("建表语句错误：\n%s", strings.Join(sqls, "\n"))
+	}
+}
+
+//表已存在时只添加缺失的字段及索引，新字段保持定义的顺序
+func TestSyncSQLAlterTable(t *testing.T) {
+	db, mock := dbtest.NewDb()
+	mock.ExpectQuery(`information_schema.TABLES`).WithArgs("sync_user").WillReturnRows([]string{"TABLE_NAME"}, []interface{}{"sync_user"})
+	mock.ExpectQuery(`information_schema.COLUMNS`).WillReturnRows([]string{"COLUMN_NAME"},
+		[]interface{}{"ID"}, []interface{}{"email"}, []interface{}{"nick"}, []interface{}{"bio"}, []interface{}{"score"},
+		[]interface{}{"status"}, []interface{}{"active"}, []interface{}{"birthday"}, []interface{}{"updated_at"})
+	mock.ExpectQuery(`information_schema.STATISTICS`).WillReturnRows([]string{"INDEX_NAME"},
+		[]interface{}{"PRIMARY"}, []interface{}{"UK_EMAIL"}, []interface{}{"uk_name_email"})
+	sqls, err := db.SyncSQL(&syncUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ALTER TABLE `sync_user` ADD COLUMN `name` VARCHAR(64) NOT NULL DEFAULT 'it''s' AFTER `id`",
+		"ALTER TABLE `sync_user` ADD COLUMN `created_at` BIGINT NOT NULL DEFAULT 0 AFTER `birthday`",
+		"ALTER TABLE `sync_user` ADD INDEX `idx_name` (`name`)",
+	}
+	if strings.Join(sqls, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("修改表结构的语句错误：\n%s", strings.Join(sqls, "\n"))
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//不支持的类型及未定义表名时返回错误
+func TestSyncSQLErrors(t *testing.T) {
+	type noTable struct {
+		Id int `field:"id"`
+	}
+	type badType struct {
+		Id   int               `field:"id" table:"bad"`
+		Tags map[string]string `field:"tags"`
+	}
+	type nullDefault struct {
+		Id   int    `field:"id" table:"null_default"`
+		Name string `field:"name" default:"null"`
+	}
+	db, _ := dbtest.NewDb()
+	for _, model := range []interface{}{&noTable{}, &badType{}, &nullDefault{}, 1} {
+		if _, err := db.SyncSQL(model); err == nil {
+			t.Errorf("%T: 应返回错误", model)
+		}
+	}
+}
+
+//字符串默认值转义反斜杠及单引号，可为空的时间字段允许默认值NULL
+func TestSyncSQLDefaultEscape(t *testing.T) {
+	type escapeModel struct {
+		Id        int       `field:"id" table:"escape_default"`
+		Path      string    `field:"path" default:"C:\\dir\\"`
+		Quote     string    `field:"quote" default:"a\\'b"`
+		DeletedAt time.Time `field:"deleted_at" default:"NULL"`
+	}
+	db, _ := dbtest.NewDb()
+	sqls, err := db.SyncSQL(&escapeModel{})
+	if err != nil || len(sqls) != 1 {
+		t.Fatalf("生成建表语句失败：%v", err)
+	}
+	for _, want := range []string{
+		"`path` VARCHAR(255) NOT NULL DEFAULT 'C:\\\\dir\\\\'",
+		"`quote` VARCHAR(255) NOT NULL DEFAULT 'a\\\\''b'",
+		"`deleted_at` DATETIME NULL DEFAULT NULL",
+	} {
+		if !strings.Contains(sqls[0], want) {
+			t.Errorf("建表语句中应包含%s：\n%s", want, sqls[0])
+		}
+	}
+}
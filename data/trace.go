@@ -0,0 +1,350 @@
+/*
+	SQL执行跟踪：执行前后的钩子、慢查询日志、SQL参数填充（敏感字段脱敏）及SQL执行次数统计
+	使用方法：
+	D("dev").AddQueryHook(&Db.SlowQueryLogger{Threshold: 200 * time.Millisecond})
+	counter := &Db.QueryCounter{}
+	D("dev").Session().Counter(counter).Table("user").Count() //Session创建独立的操作状态，统计不会计入其他请求
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package Db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/misgo/aresgo/text"
+)
+
+var (
+	RedactFields         []string      = []string{"password", "passwd", "pwd", "secret", "token"} //SQL日志中需要脱敏的字段（字段名包含即脱敏）
+	DefaultSlowThreshold time.Duration = 500 * time.Millisecond                                   //SlowQueryLogger未设置Threshold时使用的慢查询阈值
+)
+
+type (
+	//SQL执行事件
+	QueryEvent struct {
+		SQL          string
+		Args         []interface{}
+		Method       string        //操作类型：INSERT/UPDATE/DELETE/SELECT/DDL
+		IsWriter     bool          //是否在主库执行
+		StartTime    time.Time     //开始执行时间
+		Duration     time.Duration //执行耗时（BeforeQuery中为0）
+		RowsAffected int64         //修改操作为影响的行数，查询操作为返回的行数，流式查询为-1
+		Err          error         //执行错误
+
+		counter *QueryCounter
+	}
+
+	//SQL执行钩子
+	QueryHook interface {
+		BeforeQuery(e *QueryEvent)
+		AfterQuery(e *QueryEvent)
+	}
+
+	//慢查询日志，执行时间超过Threshold的SQL写入日志（参数已填充并脱敏），执行出错的SQL同时写入错误日志
+	SlowQueryLogger struct {
+		Threshold time.Duration //慢查询阈值，小于等于0时使用DefaultSlowThreshold
+		LogName   string        //日志文件名，默认为slow_sql
+	}
+
+	//SQL执行次数统计，可用于统计单个http请求执行的SQL，并发安全
+	QueryCounter struct {
+		queries  int64
+		execs    int64
+		errors   int64
+		duration int64
+	}
+)
+
+//添加SQL执行钩子，钩子对当前数据库对象的所有操作生效
+func (m *DbModel) AddQueryHook(hooks ...QueryHook) *DbModel {
+	m.hooks = append(m.hooks, hooks...)
+	return m
+}
+
+//设置SQL执行次数统计对象，只对当前操作生效
+//共用的数据库对象在并发请求中需先调用Session，避免统计对象被其他请求覆盖
+func (m *DbModel) Counter(c *QueryCounter) *DbModel {
+	m.counter = c
+	return m
+}
+
+//创建共用连接、配置及钩子的数据库对象，查询条件、统计对象等操作状态与原对象相互独立
+func (m *DbModel) Session() *DbModel {
+	s := &DbModel{
+		dbReader:        m.dbReader,
+		dbWriter:        m.dbWriter,
+		QuoteIdentifier: m.QuoteIdentifier,
+		ParamIdentifier: m.ParamIdentifier,
+		ParamIteration:  m.ParamIteration,
+		EnableTbPre:     m.EnableTbPre,
+		TbPre:           m.TbPre,
+		charset:         m.charset,
+		hooks:           m.hooks,
+		cacheStore:      m.cacheStore,
+	}
+	s.ResetDbModel()
+	return s
+}
+
+//SQL执行前调用钩子，未设置钩子及统计对象时返回nil
+func (m *DbModel) traceBefore(method string, sqlstr string, args []interface{}, isWriter bool) *QueryEvent {
+	if len(m.hooks) < 1 && m.counter == nil {
+		return nil
+	}
+	e := &QueryEvent{
+		SQL:       sqlstr,
+		Args:      args,
+		Method:    method,
+		IsWriter:  isWriter,
+		StartTime: time.Now(),
+		counter:   m.counter,
+	}
+	for _, h := range m.hooks {
+		h.BeforeQuery(e)
+	}
+	return e
+}
+
+//SQL执行后调用钩子并统计
+func (m *DbModel) traceAfter(e *QueryEvent, rows int64, err error) {
+	if e == nil {
+		return
+	}
+	e.Duration = time.Since(e.StartTime)
+	e.RowsAffected = rows
+	e.Err = err
+	if e.counter != nil {
+		e.counter.add(e)
+	}
+	for _, h := range m.hooks {
+		h.AfterQuery(e)
+	}
+}
+
+//填充参数后的SQL语句（敏感字段已脱敏），用于日志输出
+func (e *QueryEvent) String() string {
+	return Interpolate(e.SQL, e.Args)
+}
+
+func (l *SlowQueryLogger) BeforeQuery(e *QueryEvent) {}
+
+func (l *SlowQueryLogger) AfterQuery(e *QueryEvent) {
+	logName := l.LogName
+	if logName == "" {
+		logName = "slow_sql"
+	}
+	db := "slave"
+	if e.IsWriter {
+		db = "master"
+	}
+	if e.Err != nil {
+		Text.Log("sql_error").Error(fmt.Sprintf("[%s][%v] %s;error:%v", db, e.Duration, e.String(), e.Err))
+	}
+	if e.Duration >= l.threshold() {
+		Text.Log(logName).Warning(fmt.Sprintf("[%s][%v][rows:%d] %s", db, e.Duration, e.RowsAffected, e.String()))
+	}
+}
+
+//慢查询阈值，未设置时使用DefaultSlowThreshold
+func (l *SlowQueryLogger) threshold() time.Duration {
+	if l.Threshold <= 0 {
+		return DefaultSlowThreshold
+	}
+	return l.Threshold
+}
+
+//累加统计
+func (c *QueryCounter) add(e *QueryEvent) {
+	if e.IsWriter {
+		atomic.AddInt64(&c.execs, 1)
+	} else {
+		atomic.AddInt64(&c.queries, 1)
+	}
+	if e.Err != nil {
+		atomic.AddInt64(&c.errors, 1)
+	}
+	atomic.AddInt64(&c.duration, int64(e.Duration))
+}
+
+//查询次数（从库）
+func (c *QueryCounter) Queries() int64 {
+	return atomic.LoadInt64(&c.queries)
+}
+
+//修改次数（主库）
+func (c *QueryCounter) Execs() int64 {
+	return atomic.LoadInt64(&c.execs)
+}
+
+//执行出错次数
+func (c *QueryCounter) Errors() int64 {
+	return atomic.LoadInt64(&c.errors)
+}
+
+//SQL执行总耗时
+func (c *QueryCounter) Duration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.duration))
+}
+
+//SQL执行总次数
+func (c *QueryCounter) Total() int64 {
+	return c.Queries() + c.Execs()
+}
+
+//清空统计
+func (c *QueryCounter) Reset() {
+	atomic.StoreInt64(&c.queries, 0)
+	atomic.StoreInt64(&c.execs, 0)
+	atomic.StoreInt64(&c.errors, 0)
+	atomic.StoreInt64(&c.duration, 0)
+}
+
+func (c *QueryCounter) String() string {
+	return fmt.Sprintf("sql:%d(query:%d,exec:%d,error:%d) time:%v", c.Total(), c.Queries(), c.Execs(), c.Errors(), c.Duration())
+}
+
+//将参数填充到SQL语句中，RedactFields中的字段值以***代替，仅用于日志输出，不可用于执行
+func Interpolate(sqlstr string, args []interface{}) string {
+	if len(args) < 1 {
+		return sqlstr
+	}
+	positions := placeholders(sqlstr)
+	columns := placeholderColumns(sqlstr, positions)
+	sb := Text.NewString("")
+	last := 0
+	for n, pos := range positions {
+		if n >= len(args) {
+			break
+		}
+		sb.Append(sqlstr[last:pos])
+		if isRedactField(columns[n]) {
+			sb.Append("'***'")
+		} else {
+			sb.Append(formatArg(args[n]))
+		}
+		last = pos + 1
+	}
+	sb.Append(sqlstr[last:])
+	return sb.ToString()
+}
+
+//获取占位符的位置，跳过字符串及反引号标识符中的?（支持反斜杠转义及连续两个引号的转义）
+func placeholders(sqlstr string) []int {
+	var positions []int
+	var quote byte = 0
+	for i := 0; i < len(sqlstr); i++ {
+		c := sqlstr[i]
+		switch {
+		case quote != 0: //字符串内的?不是占位符
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+//获取每个占位符对应的字段名（INSERT/REPLACE按字段列表顺序对应，其他语句取占位符前的字段名）
+//@param positions placeholders返回的占位符位置
+func placeholderColumns(sqlstr string, positions []int) []string {
+	columns := make([]string, 0, len(positions))
+	upper := strings.ToUpper(strings.TrimSpace(sqlstr))
+	if strings.HasPrefix(upper, "INSERT") || strings.HasPrefix(upper, "REPLACE") {
+		start := strings.Index(sqlstr, "(")
+		end := strings.Index(sqlstr, ")")
+		if start > 0 && end > start && (len(positions) < 1 || positions[0] > end) {
+			var fields []string
+			for _, f := range strings.Split(sqlstr[start+1:end], ",") {
+				fields = append(fields, strings.Trim(strings.TrimSpace(f), "`"))
+			}
+			for i := range positions {
+				columns = append(columns, fields[i%len(fields)])
+			}
+			return columns
+		}
+	}
+	for _, pos := range positions {
+		columns = append(columns, columnBefore(sqlstr[:pos]))
+	}
+	return columns
+}
+
+//获取占位符前的字段名，跳过比较运算符及LIKE/IN等关键字
+func columnBefore(s string) string {
+	for {
+		s = strings.TrimRight(s, " \t\r\n=<>!(,?")
+		end := len(s)
+		start := end
+		for start > 0 && isIdentChar(s[start-1]) {
+			start--
+		}
+		if start == end {
+			return ""
+		}
+		word := strings.Trim(s[start:end], "`")
+		switch strings.ToUpper(word) {
+		case "LIKE", "IN", "NOT", "IS", "REGEXP", "BETWEEN", "AND":
+			s = s[:start]
+			continue
+		}
+		if idx := strings.LastIndex(word, "."); idx >= 0 { //去掉表名
+			word = word[idx+1:]
+		}
+		return word
+	}
+}
+
+//是否为标识符字符
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '`' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+//是否为需要脱敏的字段
+func isRedactField(column string) bool {
+	column = strings.ToLower(column)
+	if column == "" {
+		return false
+	}
+	for _, v := range RedactFields {
+		if strings.Contains(column, strings.ToLower(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+//格式化SQL参数
+func formatArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return Text.SpliceString("'", strings.Replace(v, "'", "''", -1), "'")
+	case []byte:
+		return Text.SpliceString("'", strings.Replace(string(v), "'", "''", -1), "'")
+	case time.Time:
+		return Text.SpliceString("'", v.Format("2006-01-02 15:04:05"), "'")
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", arg)
+}
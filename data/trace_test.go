@@ -0,0 +1,71 @@
+package Db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInterpolate(t *testing.T) {
+	cases := []struct {
+		sql  string
+		args []interface{}
+		want string
+	}{
+		{"SELECT * FROM user WHERE id = ?", []interface{}{1}, "SELECT * FROM user WHERE id = 1"},
+		{"SELECT * FROM user WHERE name = ? AND ok = ?", []interface{}{"it's", true}, "SELECT * FROM user WHERE name = 'it''s' AND ok = 1"},
+		{"SELECT * FROM user WHERE a = '?' AND password = ?", []interface{}{"x"}, "SELECT * FROM user WHERE a = '?' AND password = '***'"},
+		{"SELECT * FROM user WHERE a = 'x\\'?' AND b = ? AND pwd = ?", []interface{}{2, "p"}, "SELECT * FROM user WHERE a = 'x\\'?' AND b = 2 AND pwd = '***'"},
+		{"SELECT * FROM user WHERE `we?ird` = ? AND token IN (?)", []interface{}{nil, "t"}, "SELECT * FROM user WHERE `we?ird` = NULL AND token IN ('***')"},
+		{"INSERT INTO user (name, password) VALUES (?, ?), (?, ?)", []interface{}{"a", "p1", "b", "p2"}, "INSERT INTO user (name, password) VALUES ('a', '***'), ('b', '***')"},
+		{"UPDATE user SET secret_key = ?, age = ? WHERE id = ?", []interface{}{"s", 3.5, 1}, "UPDATE user SET secret_key = '***', age = 3.5 WHERE id = 1"},
+		{"SELECT ? , ?", []interface{}{1}, "SELECT 1 , ?"},
+		{"SELECT 1", nil, "SELECT 1"},
+	}
+	for _, c := range cases {
+		if got := Interpolate(c.sql, c.args); got != c.want {
+			t.Errorf("Interpolate(%q)\n got: %s\nwant: %s", c.sql, got, c.want)
+		}
+	}
+}
+
+//占位符与字段名一一对应，引号内的?不计入
+func TestPlaceholderColumns(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT * FROM t WHERE a = '?' AND password = ?", []string{"password"}},
+		{"SELECT * FROM t WHERE t.name LIKE ? AND age >= ? AND id NOT IN (?, ?)", []string{"name", "age", "id", "id"}},
+		{"INSERT INTO t (`a`, `pwd`) VALUES (?, '?', ?)", []string{"a", "pwd"}},
+		{"SELECT * FROM t WHERE x BETWEEN ? AND ?", []string{"x", "x"}},
+	}
+	for _, c := range cases {
+		got := placeholderColumns(c.sql, placeholders(c.sql))
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("placeholderColumns(%q) = %v, want %v", c.sql, got, c.want)
+		}
+	}
+}
+
+func TestSlowQueryThreshold(t *testing.T) {
+	if d := (&SlowQueryLogger{}).threshold(); d != DefaultSlowThreshold {
+		t.Errorf("未设置阈值时应使用DefaultSlowThreshold，实际为%v", d)
+	}
+	if d := (&SlowQueryLogger{Threshold: time.Second}).threshold(); d != time.Second {
+		t.Errorf("阈值错误：%v", d)
+	}
+}
+
+//Session的操作状态与原对象相互独立
+func TestSessionIsolated(t *testing.T) {
+	db := NewDbFromConn(nil, nil, &DbSettings{EnableTbPre: true, TbPre: "t_"})
+	c := &QueryCounter{}
+	s := db.Session().Counter(c).Table("user").Where("id = ?", 1)
+	if db.counter != nil || db.TableName != "" || db.WhereStr != "" {
+		t.Fatal("Session修改了原对象的状态")
+	}
+	if s.counter != c || s.TableName != "t_user" {
+		t.Fatalf("Session状态错误：%v %s", s.counter, s.TableName)
+	}
+}
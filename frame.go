@@ -6,12 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/misgo/aresgo/cache"
 	"github.com/misgo/aresgo/config"
 	"github.com/misgo/aresgo/data"
-	//	"github.com/misgo/aresgo/framework"
-	//	"github.com/misgo/aresgo/text"
+	"github.com/misgo/aresgo/framework"
+	"github.com/misgo/aresgo/text"
 )
 
 //常量定义
@@ -46,51 +48,208 @@ const (
 
 //初始化定义
 var (
-	StartTime string //程序启动时间
+	StartTime         string                      //程序启动时间
+	OpenRetryInterval time.Duration = time.Second //创建数据库/Redis/缓存对象失败后，在此间隔内直接返回上次的错误，避免反复连接
 	//---数据库---
-	DS           *Db.DbModel            = nil                          //当前数据库对象实例
-	DbModels     map[string]*Db.DbModel = make(map[string]*Db.DbModel) //数据库对象列表
-	DbConfigPath string                 = ""                           //数据库配置文件路径
-	dbConfiger   config.Configer        = nil                          //数据库配置文件对象
+	DS           *Db.DbModel             = nil                           //当前数据库对象实例
+	DbModels     map[string]*Db.DbModel  = make(map[string]*Db.DbModel)  //数据库对象列表
+	DbConfigPath string                  = ""                            //数据库配置文件路径
+	dbConfiger   config.Configer         = nil                           //数据库配置文件对象
+	ShardModels  map[string]*Db.Sharding = make(map[string]*Db.Sharding) //分片规则列表
+	dbMu         sync.RWMutex                                            //数据库对象列表锁
+	shardMu      sync.RWMutex                                            //分片规则列表锁
+	configMu     sync.Mutex                                              //数据库配置文件加载锁
 	//---Redis缓存---
 	RS              *Cache.RedisModel            = nil                                //当前Redis对象实例
 	RedisModels     map[string]*Cache.RedisModel = make(map[string]*Cache.RedisModel) //Redis对象列表
+	redisMu         sync.RWMutex                                                      //Redis对象列表锁
 	RedisConfigPath string                       = ""                                 //Redis配置文件路径
 	CacheConfigPath string                       = ""                                 //缓存配置文件路径
+	//---通用缓存---
+	CacheModels map[string]Cache.Cache = make(map[string]Cache.Cache) //缓存对象列表，Key为Redis配置的Key
+	cacheMu     sync.Mutex                                            //缓存对象列表锁
 	//---用户自定义---
 	CustomVar     map[string]interface{} //用户自定义全局变量
 	TemplatePaths map[string][]string    //用户自定义页面模板列表
 
+	dbOpens    openGroup //数据库对象的创建操作
+	redisOpens openGroup //Redis对象的创建操作
+	cacheOpens openGroup //缓存对象的创建操作
+	shardOpens openGroup //分片规则的创建操作
 )
 
+type (
+	//按Key合并并发的创建操作，创建过程不持有对象列表的锁
+	openGroup struct {
+		mu    sync.Mutex
+		calls map[string]*openCall
+	}
+	//进行中或失败的创建操作
+	openCall struct {
+		done   chan struct{}
+		val    interface{}
+		err    error
+		expire time.Time //失败结果的过期时间
+	}
+)
+
+//执行Key对应的创建操作，同一Key同时只执行一次，其他协程等待并共享结果
+//创建失败时在OpenRetryInterval内直接返回上次的错误
+func (g *openGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*openCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		select {
+		case <-c.done: //上次创建失败
+			if time.Now().Before(c.expire) {
+				g.mu.Unlock()
+				return nil, c.err
+			}
+		default: //创建中，等待结果
+			g.mu.Unlock()
+			<-c.done
+			return c.val, c.err
+		}
+	}
+	c := &openCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+	g.mu.Lock()
+	if c.err == nil {
+		delete(g.calls, key)
+	} else {
+		c.expire = time.Now().Add(OpenRetryInterval)
+	}
+	g.mu.Unlock()
+	close(c.done)
+	return c.val, c.err
+}
+
+//清除Key对应的失败结果，添加或移除对象后立即重新创建
+func (g *openGroup) forget(key string) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		select {
+		case <-c.done:
+			delete(g.calls, key)
+		default:
+		}
+	}
+	g.mu.Unlock()
+}
+
+//清除所有已结束的失败结果
+func (g *openGroup) forgetAll() {
+	g.mu.Lock()
+	for key, c := range g.calls {
+		select {
+		case <-c.done:
+			delete(g.calls, key)
+		default:
+		}
+	}
+	g.mu.Unlock()
+}
+
 //初始化数据库配置
 func InitMysql(config map[string]*Db.DbSettings) {
 	DS = Db.NewDb("mysql", config)
 }
 
-//通过Key获取数据库访问对象
+//通过Key获取数据库访问对象，获取失败时记录日志并返回没有连接的对象，需要处理错误时使用OpenDb
 func D(dbkey string) *Db.DbModel {
-	var ds *Db.DbModel = nil
-	if _, ok := DbModels[dbkey]; ok { //能取到数据库对象
-		ds = DbModels[dbkey]
+	db, err := OpenDb(dbkey)
+	if err != nil {
+		Text.Log("error").Error(fmt.Sprintf("db[%s] open error:%s", dbkey, err.Error()))
+		return &Db.DbModel{}
 	}
-	if ds == nil { //数据库对象不存在
-		err := getDbModel(dbkey)
+	return db
+}
+
+//通过Key获取数据库访问对象，未创建时根据配置文件创建并检查连接，并发安全
+//同一Key的创建操作只执行一次，连接过程不阻塞其他Key；失败后OpenRetryInterval内直接返回错误
+func OpenDb(dbkey string) (*Db.DbModel, error) {
+	dbMu.RLock()
+	db, ok := DbModels[dbkey]
+	dbMu.RUnlock()
+	if ok {
+		return db, nil
+	}
+	v, err := dbOpens.do(dbkey, func() (interface{}, error) {
+		dbMu.RLock()
+		db, ok := DbModels[dbkey]
+		dbMu.RUnlock()
+		if ok { //其他协程已创建
+			return db, nil
+		}
+		db, err := getDbModel(dbkey)
 		if err != nil {
-			return &Db.DbModel{}
+			return nil, fmt.Errorf("数据库[%s]初始化失败：%s", dbkey, err.Error())
 		}
+		dbMu.Lock()
+		if old, ok := DbModels[dbkey]; ok { //创建过程中已通过AddDb添加
+			dbMu.Unlock()
+			db.Close()
+			return old, nil
+		}
+		DbModels[dbkey] = db
+		dbMu.Unlock()
+		return db, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Db.DbModel), nil
+}
+
+//通过Key获取数据库访问对象，获取失败时panic，用于程序启动时初始化
+func MustDb(dbkey string) *Db.DbModel {
+	db, err := OpenDb(dbkey)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+//添加数据库对象，Key已存在时替换并关闭原对象
+func AddDb(dbkey string, db *Db.DbModel) {
+	dbMu.Lock()
+	old, ok := DbModels[dbkey]
+	DbModels[dbkey] = db
+	dbMu.Unlock()
+	dbOpens.forget(dbkey)
+	if ok && old != db {
+		old.Close()
 	}
-	return DbModels[dbkey]
+	resetShards()
 }
 
-//获取数据对象
-func getDbModel(dbkey string) error {
+//移除并关闭数据库对象
+func RemoveDb(dbkey string) error {
+	dbMu.Lock()
+	db, ok := DbModels[dbkey]
+	delete(DbModels, dbkey)
+	dbMu.Unlock()
+	dbOpens.forget(dbkey)
+	if !ok {
+		return fmt.Errorf("数据库[%s]不存在", dbkey)
+	}
+	resetShards()
+	return db.Close()
+}
+
+//根据配置文件创建数据库对象
+func getDbModel(dbkey string) (*Db.DbModel, error) {
 	//如果数据库配置文件未加载，则先加载配置文件
-	if dbConfiger == nil {
-		err := loadDbConfig()
-		if err != nil {
-			return err
-		}
+	if err := loadDbConfig(); err != nil {
+		return nil, err
+	}
+	if _, err := dbConfiger.GetVal(dbkey); err != nil {
+		return nil, errors.New("未找到数据库配置")
 	}
 	//设置数据库主从配置，从配置文件中获取
 	var settings map[string]*Db.DbSettings = make(map[string]*Db.DbSettings, 2)
@@ -120,13 +279,122 @@ func getDbModel(dbkey string) error {
 	}
 	settings["master"] = dbwriter
 	settings["slave"] = dbreader
-	db := Db.NewDb("mysql", settings)
-	DbModels[dbkey] = db
-	return nil
+	db, err := Db.OpenDb("mysql", settings)
+	if err != nil {
+		return nil, err
+	}
+	//慢查询日志，单位：毫秒
+	if slowMs := dbConfiger.DefaultInt(fmt.Sprintf("%s.slow_query", dbkey), 0); slowMs > 0 {
+		db.AddQueryHook(&Db.SlowQueryLogger{Threshold: time.Duration(slowMs) * time.Millisecond})
+	}
+	//查询结果缓存：memory为进程内缓存，其他值为Redis配置的Key
+	if cacheKey := dbConfiger.DefaultString(fmt.Sprintf("%s.cache", dbkey), ""); cacheKey == "memory" {
+		db.SetCacheStore(Cache.NewMemoryCache(Cache.DefaultMaxEntries))
+	} else if cacheKey != "" {
+		rs, err := OpenRedis(cacheKey)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		db.SetCacheStore(Cache.NewRedisCache(rs))
+	}
+	return db, nil
 }
 
-//加载数据库配置文件
+//通过逻辑表名获取分片规则，规则配置在数据库配置文件的sharding节点下，如：
+/*
+"sharding": {
+	"t_order": {"type": "mod", "dbs": "order0;order1", "tables": 64, "format": "%s_%02d"},
+	"t_log": {"type": "range", "dbs": "log0;log1", "tables": 2, "ranges": [[0, 9999999, 0], [10000000, 19999999, 1]]}
+}
+*/
+//使用时：db, err := aresgo.Shard("t_order").ShardBy(uid)，再执行db.Where("uid = ?", uid).FindList(&orders)
+func Shard(table string) *Db.Sharding {
+	shardMu.RLock()
+	s, ok := ShardModels[table]
+	shardMu.RUnlock()
+	if ok {
+		return s
+	}
+	//创建规则时会连接数据库，不持有规则列表的锁；失败后OpenRetryInterval内直接返回错误
+	v, err := shardOpens.do(table, func() (interface{}, error) {
+		shardMu.RLock()
+		s, ok := ShardModels[table]
+		shardMu.RUnlock()
+		if ok { //其他协程已创建
+			return s, nil
+		}
+		s, err := getSharding(table)
+		if err != nil {
+			return nil, err
+		}
+		shardMu.Lock()
+		ShardModels[table] = s
+		shardMu.Unlock()
+		return s, nil
+	})
+	if err != nil { //未获取到分片规则时返回没有数据库实例的规则，路由时返回错误
+		Text.Log("db_error").Error(fmt.Sprintf("shard[%s] config error:%s", table, err.Error()))
+		return Db.NewSharding(table)
+	}
+	return v.(*Db.Sharding)
+}
+
+//根据配置文件创建分片规则
+func getSharding(table string) (*Db.Sharding, error) {
+	if err := loadDbConfig(); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("sharding.%s", table)
+	if _, err := dbConfiger.GetVal(key); err != nil {
+		return nil, errors.New("未找到分片配置")
+	}
+	var dbs []*Db.DbModel
+	for _, dbkey := range dbConfiger.Strings(key + ".dbs") {
+		db, err := OpenDb(dbkey)
+		if err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, db)
+	}
+	if len(dbs) < 1 {
+		return nil, errors.New("未设置分片的数据库")
+	}
+	s := Db.NewSharding(table, dbs...)
+	s.Type = dbConfiger.DefaultString(key+".type", Db.ShardTypeMod)
+	s.TableCount = dbConfiger.DefaultInt(key+".tables", 0)
+	s.TableFormat = dbConfiger.DefaultString(key+".format", Db.DefaultShardTableFormat)
+	if ranges, err := dbConfiger.GetVal(key + ".ranges"); err == nil {
+		list, _ := ranges.([]interface{})
+		for _, item := range list {
+			r, ok := item.([]interface{})
+			if !ok || len(r) != 3 {
+				return nil, errors.New("分片范围格式必须为[最小值, 最大值, 表序号]")
+			}
+			min, _ := r[0].(float64)
+			max, _ := r[1].(float64)
+			index, _ := r[2].(float64)
+			s.Ranges = append(s.Ranges, Db.ShardRange{Min: int64(min), Max: int64(max), Index: int(index)})
+		}
+	}
+	return s, nil
+}
+
+//清空分片规则，数据库对象变更后重新创建
+func resetShards() {
+	shardMu.Lock()
+	ShardModels = make(map[string]*Db.Sharding)
+	shardMu.Unlock()
+	shardOpens.forgetAll()
+}
+
+//加载数据库配置文件，已加载时不重复加载
 func loadDbConfig() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if dbConfiger != nil {
+		return nil
+	}
 	if DbConfigPath != "" {
 		conf, err := config.NewConfig("json", DbConfigPath)
 		if err == nil { //获取成功
@@ -140,57 +408,259 @@ func loadDbConfig() error {
 	}
 }
 
-//通过Key获取Redis访问对象
+//通过Key获取Redis访问对象，获取失败时记录日志并返回没有连接的对象，需要处理错误时使用OpenRedis
 func R(redisKey string) *Cache.RedisModel {
-	var rs *Cache.RedisModel = nil
-	if _, ok := RedisModels[redisKey]; ok {
-		rs = RedisModels[redisKey]
+	rs, err := OpenRedis(redisKey)
+	if err != nil {
+		Text.Log("error").Error(fmt.Sprintf("redis[%s] open error:%s", redisKey, err.Error()))
+		return &Cache.RedisModel{}
+	}
+	return rs
+}
+
+//通过Key获取Redis访问对象，未创建时根据配置文件创建并检查连接，并发安全
+//同一Key的创建操作只执行一次，连接过程不阻塞其他Key；失败后OpenRetryInterval内直接返回错误
+func OpenRedis(redisKey string) (*Cache.RedisModel, error) {
+	redisMu.RLock()
+	rs, ok := RedisModels[redisKey]
+	redisMu.RUnlock()
+	if ok {
+		return rs, nil
 	}
-	if rs == nil {
-		err := getRedisModel(redisKey)
+	v, err := redisOpens.do(redisKey, func() (interface{}, error) {
+		redisMu.RLock()
+		rs, ok := RedisModels[redisKey]
+		redisMu.RUnlock()
+		if ok { //其他协程已创建
+			return rs, nil
+		}
+		rs, err := getRedisModel(redisKey)
 		if err != nil {
-			return &Cache.RedisModel{}
+			return nil, fmt.Errorf("Redis[%s]初始化失败：%s", redisKey, err.Error())
+		}
+		redisMu.Lock()
+		if old, ok := RedisModels[redisKey]; ok { //创建过程中已通过AddRedis添加
+			redisMu.Unlock()
+			rs.Close()
+			return old, nil
 		}
+		RedisModels[redisKey] = rs
+		redisMu.Unlock()
+		return rs, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return RedisModels[redisKey]
+	return v.(*Cache.RedisModel), nil
 }
 
-//获取Redis实例
-func getRedisModel(redisKey string) error {
-	if RedisConfigPath != "" {
-		redisConfiger, err := LoadConfig("json", RedisConfigPath)
-		if err == nil {
-			var settings map[string]*Cache.RedisSettings = make(map[string]*Cache.RedisSettings, 2)
-			settings["master"] = &Cache.RedisSettings{
-				IP:          redisConfiger.DefaultString(fmt.Sprintf("%s.master.ip", redisKey), "127.0.0.1"),
-				Port:        redisConfiger.DefaultString(fmt.Sprintf("%s.master.port", redisKey), "6379"),
-				Password:    redisConfiger.DefaultString(fmt.Sprintf("%s.master.password", redisKey), ""),
-				DbNum:       redisConfiger.DefaultInt(fmt.Sprintf("%s.master.db", redisKey), 0),
-				MaxIdle:     redisConfiger.DefaultInt(fmt.Sprintf("%s.master.maxidle", redisKey), 3),
-				MaxActive:   redisConfiger.DefaultInt(fmt.Sprintf("%s.master.maxactive", redisKey), 1000),
-				IdleTimeout: redisConfiger.DefaultInt(fmt.Sprintf("%s.master.idletimeout", redisKey), 180),
-				KeyPre:      redisConfiger.DefaultString(fmt.Sprintf("%s.master.key_pre", redisKey), "misgo_"),
+//通过Key获取Redis访问对象，获取失败时panic，用于程序启动时初始化
+func MustRedis(redisKey string) *Cache.RedisModel {
+	rs, err := OpenRedis(redisKey)
+	if err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+//添加Redis对象，Key已存在时替换并关闭原对象
+func AddRedis(redisKey string, rs *Cache.RedisModel) {
+	redisMu.Lock()
+	old, ok := RedisModels[redisKey]
+	RedisModels[redisKey] = rs
+	redisMu.Unlock()
+	redisOpens.forget(redisKey)
+	if ok && old != rs {
+		old.Close()
+	}
+}
+
+//移除并关闭Redis对象
+func RemoveRedis(redisKey string) error {
+	redisMu.Lock()
+	rs, ok := RedisModels[redisKey]
+	delete(RedisModels, redisKey)
+	redisMu.Unlock()
+	redisOpens.forget(redisKey)
+	if !ok {
+		return fmt.Errorf("Redis[%s]不存在", redisKey)
+	}
+	return rs.Close()
+}
+
+//通过Redis配置的Key获取缓存对象，按frame.CacheMode（memory/redis/layered）创建，获取失败时记录日志并panic，需要处理错误时使用OpenCache
+func C(redisKey string) Cache.Cache {
+	c, err := OpenCache(redisKey)
+	if err != nil {
+		Text.Log("error").Error(fmt.Sprintf("cache[%s] open error:%s", redisKey, err.Error()))
+		panic(err)
+	}
+	return c
+}
+
+//通过Redis配置的Key获取缓存对象，未创建时按frame.CacheMode创建，并发安全
+func OpenCache(redisKey string) (Cache.Cache, error) {
+	cacheMu.Lock()
+	c, ok := CacheModels[redisKey]
+	cacheMu.Unlock()
+	if ok {
+		return c, nil
+	}
+	v, err := cacheOpens.do(redisKey, func() (interface{}, error) {
+		var rs *Cache.RedisModel
+		if mode := strings.ToLower(frame.CacheMode); mode == Cache.CacheModeRedis || mode == Cache.CacheModeLayered {
+			var err error
+			if rs, err = OpenRedis(redisKey); err != nil {
+				return nil, err
 			}
+		}
+		c, err := Cache.NewDefaultCache(rs)
+		if err != nil {
+			return nil, err
+		}
+		cacheMu.Lock()
+		if old, ok := CacheModels[redisKey]; ok {
+			cacheMu.Unlock()
+			return old, nil
+		}
+		CacheModels[redisKey] = c
+		cacheMu.Unlock()
+		return c, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(Cache.Cache), nil
+}
+
+//根据配置文件创建Redis对象
+func getRedisModel(redisKey string) (*Cache.RedisModel, error) {
+	if RedisConfigPath == "" {
+		return nil, errors.New("未设置Redis配置文件路径！")
+	}
+	redisConfiger, err := LoadConfig("json", RedisConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = redisConfiger.GetVal(redisKey); err != nil {
+		return nil, errors.New("未找到Redis配置")
+	}
+	var settings map[string]*Cache.RedisSettings = make(map[string]*Cache.RedisSettings, 2)
+	settings["master"] = &Cache.RedisSettings{
+		IP:          redisConfiger.DefaultString(fmt.Sprintf("%s.master.ip", redisKey), "127.0.0.1"),
+		Port:        redisConfiger.DefaultString(fmt.Sprintf("%s.master.port", redisKey), "6379"),
+		Password:    redisConfiger.DefaultString(fmt.Sprintf("%s.master.password", redisKey), ""),
+		DbNum:       redisConfiger.DefaultInt(fmt.Sprintf("%s.master.db", redisKey), 0),
+		MaxIdle:     redisConfiger.DefaultInt(fmt.Sprintf("%s.master.maxidle", redisKey), 3),
+		MaxActive:   redisConfiger.DefaultInt(fmt.Sprintf("%s.master.maxactive", redisKey), 1000),
+		IdleTimeout: redisConfiger.DefaultInt(fmt.Sprintf("%s.master.idletimeout", redisKey), 180),
+		KeyPre:      redisConfiger.DefaultString(fmt.Sprintf("%s.master.key_pre", redisKey), "misgo_"),
 
-			settings["slave"] = &Cache.RedisSettings{
-				IP:          redisConfiger.DefaultString(fmt.Sprintf("%s.slave.ip", redisKey), "127.0.0.1"),
-				Port:        redisConfiger.DefaultString(fmt.Sprintf("%s.slave.port", redisKey), "6379"),
-				Password:    redisConfiger.DefaultString(fmt.Sprintf("%s.slave.password", redisKey), ""),
-				DbNum:       redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.db", redisKey), 0),
-				MaxIdle:     redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.maxidle", redisKey), 3),
-				MaxActive:   redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.maxactive", redisKey), 1000),
-				IdleTimeout: redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.idletimeout", redisKey), 180),
-				KeyPre:      redisConfiger.DefaultString(fmt.Sprintf("%s.slave.key_pre", redisKey), "misgo_"),
+		PrefixChannel: redisConfiger.DefaultBool(fmt.Sprintf("%s.master.prefix_channel", redisKey), false),
+		Codec:         redisConfiger.DefaultString(fmt.Sprintf("%s.master.codec", redisKey), ""),
+		Compress:      redisConfiger.DefaultBool(fmt.Sprintf("%s.master.compress", redisKey), false),
+
+		Sentinels:        redisConfiger.DefaultStrings(fmt.Sprintf("%s.master.sentinels", redisKey), nil),
+		MasterName:       redisConfiger.DefaultString(fmt.Sprintf("%s.master.master_name", redisKey), ""),
+		SentinelPassword: redisConfiger.DefaultString(fmt.Sprintf("%s.master.sentinel_password", redisKey), ""),
+
+		ClusterNodes: redisConfiger.DefaultStrings(fmt.Sprintf("%s.master.cluster_nodes", redisKey), nil),
+	}
+
+	settings["slave"] = &Cache.RedisSettings{
+		IP:          redisConfiger.DefaultString(fmt.Sprintf("%s.slave.ip", redisKey), "127.0.0.1"),
+		Port:        redisConfiger.DefaultString(fmt.Sprintf("%s.slave.port", redisKey), "6379"),
+		Password:    redisConfiger.DefaultString(fmt.Sprintf("%s.slave.password", redisKey), ""),
+		DbNum:       redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.db", redisKey), 0),
+		MaxIdle:     redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.maxidle", redisKey), 3),
+		MaxActive:   redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.maxactive", redisKey), 1000),
+		IdleTimeout: redisConfiger.DefaultInt(fmt.Sprintf("%s.slave.idletimeout", redisKey), 180),
+		KeyPre:      redisConfiger.DefaultString(fmt.Sprintf("%s.slave.key_pre", redisKey), "misgo_"),
+
+		PrefixChannel: redisConfiger.DefaultBool(fmt.Sprintf("%s.slave.prefix_channel", redisKey), false),
+		Codec:         redisConfiger.DefaultString(fmt.Sprintf("%s.slave.codec", redisKey), ""),
+		Compress:      redisConfiger.DefaultBool(fmt.Sprintf("%s.slave.compress", redisKey), false),
+
+		Sentinels:        redisConfiger.DefaultStrings(fmt.Sprintf("%s.slave.sentinels", redisKey), nil),
+		MasterName:       redisConfiger.DefaultString(fmt.Sprintf("%s.slave.master_name", redisKey), ""),
+		SentinelPassword: redisConfiger.DefaultString(fmt.Sprintf("%s.slave.sentinel_password", redisKey), ""),
+
+		ClusterNodes: redisConfiger.DefaultStrings(fmt.Sprintf("%s.slave.cluster_nodes", redisKey), nil),
+	}
+	return Cache.OpenRedis(settings)
+}
+
+//创建配置文件中的所有数据库及Redis对象并检查连接，用于程序启动时检查配置，返回所有失败的实例信息
+func OpenAll() error {
+	var errs []string
+	if DbConfigPath != "" {
+		if err := loadDbConfig(); err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			for _, dbkey := range dbConfiger.GetKeys() {
+				if dbkey == "sharding" {
+					continue
+				}
+				if _, err := OpenDb(dbkey); err != nil {
+					errs = append(errs, err.Error())
+				}
 			}
-			RedisModels[redisKey] = Cache.NewRedis(settings)
-			return nil
+		}
+	}
+	if RedisConfigPath != "" {
+		redisConfiger, err := LoadConfig("json", RedisConfigPath)
+		if err != nil {
+			errs = append(errs, err.Error())
 		} else {
-			return err
+			for _, redisKey := range redisConfiger.GetKeys() {
+				if _, err := OpenRedis(redisKey); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
 		}
-	} else {
-		return errors.New("未设置Redis配置文件路径！")
 	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "；"))
+	}
+	return nil
+}
 
+//关闭所有数据库及Redis连接池并取消缓存的订阅，用于程序退出前释放连接
+func Close() error {
+	var errs []string
+	dbMu.Lock()
+	for dbkey, db := range DbModels {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("数据库[%s]关闭失败：%s", dbkey, err.Error()))
+		}
+	}
+	DbModels = make(map[string]*Db.DbModel)
+	if DS != nil {
+		DS.Close()
+		DS = nil
+	}
+	dbMu.Unlock()
+	resetShards()
+	cacheMu.Lock()
+	for _, c := range CacheModels {
+		if layered, ok := c.(*Cache.LayeredCache); ok {
+			layered.Close()
+		}
+	}
+	CacheModels = make(map[string]Cache.Cache)
+	cacheMu.Unlock()
+	redisMu.Lock()
+	for redisKey, rs := range RedisModels {
+		if err := rs.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("Redis[%s]关闭失败：%s", redisKey, err.Error()))
+		}
+	}
+	RedisModels = make(map[string]*Cache.RedisModel)
+	redisMu.Unlock()
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "；"))
+	}
+	return nil
 }
 
 //加载配置文件
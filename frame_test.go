@@ -0,0 +1,131 @@
+package aresgo
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//同一Key并发创建只执行一次，其他协程共享结果
+func TestOpenGroupSharesCall(t *testing.T) {
+	var g openGroup
+	var calls int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.do("a", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return 1, nil
+			})
+			if err != nil || v.(int) != 1 {
+				t.Errorf("结果错误：%v %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("创建操作应只执行一次，实际为%d次", calls)
+	}
+}
+
+//创建失败后在重试间隔内返回上次的错误，过期或forget后重新创建
+func TestOpenGroupCachesFailure(t *testing.T) {
+	old := OpenRetryInterval
+	OpenRetryInterval = 50 * time.Millisecond
+	defer func() { OpenRetryInterval = old }()
+
+	var g openGroup
+	calls := 0
+	fail := errors.New("dial error")
+	fn := func() (interface{}, error) {
+		calls++
+		return nil, fail
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := g.do("a", fn); err != fail {
+			t.Fatalf("应返回创建的错误，实际为%v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("重试间隔内不应重新创建，实际执行%d次", calls)
+	}
+	time.Sleep(60 * time.Millisecond)
+	g.do("a", fn)
+	if calls != 2 {
+		t.Fatalf("重试间隔过后应重新创建，实际执行%d次", calls)
+	}
+	g.forget("a")
+	g.do("a", fn)
+	if calls != 3 {
+		t.Fatalf("forget后应重新创建，实际执行%d次", calls)
+	}
+	if _, err := g.do("b", func() (interface{}, error) { return 2, nil }); err != nil {
+		t.Fatal("其他Key不受影响")
+	}
+}
+
+//获取失败时D/R记录日志并返回没有连接的对象，MustDb/MustRedis panic
+func TestOpenFailureFallback(t *testing.T) {
+	if db := D("no_such_db"); db == nil {
+		t.Error("D获取失败时应返回对象")
+	}
+	if rs := R("no_such_redis"); rs == nil {
+		t.Error("R获取失败时应返回对象")
+	}
+	for name, fn := range map[string]func(){
+		"MustDb":    func() { MustDb("no_such_db") },
+		"MustRedis": func() { MustRedis("no_such_redis") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s获取失败时应panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+//forgetAll清除所有失败结果
+func TestOpenGroupForgetAll(t *testing.T) {
+	var g openGroup
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return nil, errors.New("dial error")
+	}
+	g.do("a", fn)
+	g.do("b", fn)
+	g.forgetAll()
+	g.do("a", fn)
+	g.do("b", fn)
+	if calls != 4 {
+		t.Fatalf("forgetAll后应重新创建，实际执行%d次", calls)
+	}
+}
+
+//未获取到分片规则时返回没有数据库实例的规则，不持有规则列表的锁
+func TestShardConfigError(t *testing.T) {
+	s := Shard("no_such_table")
+	if s == nil || s.Table != "no_such_table" {
+		t.Fatalf("应返回没有数据库实例的规则：%+v", s)
+	}
+	if _, err := s.ShardBy(1); err == nil {
+		t.Error("没有数据库实例的规则路由时应返回错误")
+	}
+	shardMu.RLock()
+	_, ok := ShardModels["no_such_table"]
+	shardMu.RUnlock()
+	if ok {
+		t.Error("失败的规则不应加入规则列表")
+	}
+}
@@ -0,0 +1,77 @@
+/*
+	Redis健康检查及监控接口，输出各Redis对象的INFO统计及缓存池统计（Json），任意一个Redis出错时返回503
+	使用方法：
+	router.Get("/health/redis", aresgo.RedisHealth())           //所有已创建的Redis对象
+	router.Get("/health/cache", aresgo.RedisHealth("default")) //指定的Redis对象，未创建时根据配置文件创建
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package aresgo
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/router/fasthttp"
+	"github.com/misgo/aresgo/text"
+)
+
+//单个Redis对象的健康状态
+type RedisHealthStatus struct {
+	Status string            `json:"status"` //ok/error
+	Error  string            `json:"error,omitempty"`
+	Stats  *Cache.RedisStats `json:"stats,omitempty"`
+}
+
+//Redis健康检查路由函数，输出Redis对象Key对应的健康状态
+//@param redisKeys Redis对象的Key（可选），为空时检查所有已创建的Redis对象
+func RedisHealth(redisKeys ...string) HandlerFunc {
+	return func(ctx *Context) {
+		res := make(map[string]*RedisHealthStatus)
+		healthy := true
+		for redisKey, rs := range healthRedisModels(redisKeys, res) {
+			stats, err := rs.Stat()
+			status := &RedisHealthStatus{Status: "ok", Stats: stats}
+			if err != nil {
+				status.Status, status.Error = "error", err.Error()
+			}
+			res[redisKey] = status
+		}
+		for redisKey, status := range res {
+			if status.Status != "ok" {
+				healthy = false
+				Text.Log("error").Error(fmt.Sprintf("redis[%s] health check error:%s", redisKey, status.Error))
+			}
+		}
+		if !healthy {
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			ctx.ToJson(res, strconv.Itoa(fasthttp.StatusServiceUnavailable), "Redis不可用")
+			return
+		}
+		ctx.ToJson(res)
+	}
+}
+
+//需要检查的Redis对象，初始化失败的记录到res中
+func healthRedisModels(redisKeys []string, res map[string]*RedisHealthStatus) map[string]*Cache.RedisModel {
+	models := make(map[string]*Cache.RedisModel)
+	if len(redisKeys) < 1 {
+		redisMu.RLock()
+		for redisKey, rs := range RedisModels {
+			models[redisKey] = rs
+		}
+		redisMu.RUnlock()
+		return models
+	}
+	for _, redisKey := range redisKeys {
+		rs, err := OpenRedis(redisKey)
+		if err != nil {
+			res[redisKey] = &RedisHealthStatus{Status: "error", Error: err.Error()}
+			continue
+		}
+		models[redisKey] = rs
+	}
+	return models
+}
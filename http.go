@@ -19,6 +19,8 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/misgo/aresgo/data"
+	"github.com/misgo/aresgo/framework"
 	"github.com/misgo/aresgo/router/fasthttp"
 	"github.com/misgo/aresgo/text"
 )
@@ -48,6 +50,7 @@ type (
 		CrossOrigin      string
 		Errors           []*Err //请求上下文的错误列表
 		Datas            map[string]interface{}
+		queryCounter     *Db.QueryCounter //当前请求的SQL执行统计
 	}
 
 	HandlerFunc func(*Context)      //路由分发函数
@@ -350,6 +353,7 @@ func (r *Router) Handler(ctx *Context) {
 	ServerClientInfo(ctx)
 	//生成上下文存储空间
 	ctx.Datas = make(map[string]interface{})
+	ctx.queryCounter = nil
 
 	//-----回调处理--------
 
@@ -375,6 +379,9 @@ func (r *Router) Handler(ctx *Context) {
 			//计算程序执行时间
 			elapsed := time.Since(timenow)
 			fmt.Println("elapsed time: ", elapsed)
+			if ctx.queryCounter != nil && frame.Debug { //调试模式记录当前请求的SQL执行统计
+				Text.Log("debug").Debug(fmt.Sprintf("%s elapsed:%v %s", path, elapsed, ctx.queryCounter.String()))
+			}
 			return
 		} else if method != ActionConn && path != "/" {
 			code := 301 // 永久重定向
@@ -605,6 +612,19 @@ func (ctx *Context) ToXml(datas interface{}) {
 	ctx.Response.SetBody(body)
 }
 
+//获取当前请求的SQL执行统计对象
+func (ctx *Context) QueryCounter() *Db.QueryCounter {
+	if ctx.queryCounter == nil {
+		ctx.queryCounter = &Db.QueryCounter{}
+	}
+	return ctx.queryCounter
+}
+
+//通过Key获取当前请求独立的数据库访问对象（Session），执行的SQL计入当前请求的统计
+func (ctx *Context) D(dbkey string) *Db.DbModel {
+	return D(dbkey).Session().Counter(ctx.QueryCounter())
+}
+
 //----上下文处理方法----------end------------------------------------
 
 //-----Cookie & Session -----start--------------------------------
@@ -0,0 +1,320 @@
+/*
+	基于Redis的可靠任务队列，支持延迟执行、优先级、失败重试（指数退避）及死信队列
+	默认使用Stream（XREADGROUP/XACK，Redis 5.0以上），旧版本Redis可使用List（RPOPLPUSH，阻塞等待新任务通知）
+	同一队列的Key使用{队列名}作为hashtag，集群模式下在同一槽位
+	使用方法：
+	q, err := queue.New(r) //r为*Cache.RedisModel，旧版本Redis使用queue.New(r, queue.ModeList)
+	id, err := q.Enqueue("sms", map[string]string{"mobile": "138..."}, &queue.Options{Delay: time.Minute, Priority: queue.PriorityHigh})
+	w := q.NewWorker("sms", func(job *queue.Job) error {
+		var msg map[string]string
+		if err := job.Bind(&msg); err != nil {
+			return err
+		}
+		return sendSms(msg)
+	})
+	w.Concurrency = 20
+	err = w.Start()
+	defer w.Stop(30 * time.Second)
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/cache/redigo/redis"
+)
+
+const (
+	ModeStream = "stream" //使用Stream（XREADGROUP/XACK）
+	ModeList   = "list"   //使用List（RPOPLPUSH），用于不支持Stream的Redis
+
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+var (
+	DefaultMaxRetries = 3            //默认最大重试次数
+	DeadLetterMax     = int64(10000) //死信队列保留的最大任务数
+	PromoteBatch      = 100          //每次转移到期延迟任务的最大个数
+	NotifyMax         = int64(1000)  //List模式保留的新任务通知的最大个数
+	ErrEmptyQueueName = errors.New("队列名称不可以为空")
+
+	//将到期的延迟任务转移到对应优先级的待执行队列
+	//KEYS[1]延迟任务，KEYS[2-4]低、普通、高优先级的待执行队列，KEYS[5]新任务通知；ARGV[1]当前时间（毫秒），ARGV[2]最大个数，ARGV[3]模式，ARGV[4]通知的最大个数
+	promoteScript = `
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, m in ipairs(jobs) do
+	redis.call('ZREM', KEYS[1], m)
+	local p = tonumber(cjson.decode(m)['priority']) or 0
+	if p < -1 then p = -1 elseif p > 1 then p = 1 end
+	if ARGV[3] == 'stream' then
+		redis.call('XADD', KEYS[3 + p], '*', 'job', m)
+	else
+		redis.call('LPUSH', KEYS[3 + p], m)
+		redis.call('LPUSH', KEYS[5], 1)
+	end
+end
+if #jobs > 0 and ARGV[3] ~= 'stream' then
+	redis.call('LTRIM', KEYS[5], 0, tonumber(ARGV[4]) - 1)
+end
+return #jobs`
+)
+
+type (
+	//队列客户端
+	Queue struct {
+		Mode   string //队列模式：stream/list
+		Prefix string //Key前缀（在RedisModel的Key前缀之后），默认为"queue:"
+		Group  string //Stream模式的消费组名称，默认为"workers"
+		r      *Cache.RedisModel
+	}
+
+	//任务
+	Job struct {
+		Id         string `json:"id"`
+		Queue      string `json:"queue"`
+		Payload    []byte `json:"payload"`
+		Priority   int    `json:"priority"`
+		Attempts   int    `json:"attempts"`    //已失败的次数
+		MaxRetries int    `json:"max_retries"` //最大重试次数，小于0不重试
+		CreatedAt  int64  `json:"created_at"`  //创建时间（毫秒时间戳）
+		Error      string `json:"error"`       //最后一次失败的原因
+	}
+
+	//Enqueue的选项
+	Options struct {
+		Id         string        //任务Id，为空时自动生成
+		Delay      time.Duration //延迟执行的时间
+		Priority   int           //优先级：PriorityLow/PriorityNormal/PriorityHigh
+		MaxRetries int           //最大重试次数，0使用DefaultMaxRetries，小于0不重试
+	}
+)
+
+//创建队列客户端，加载脚本失败时返回错误
+//@param mode 队列模式（可选），默认为ModeStream
+func New(r *Cache.RedisModel, mode ...string) (*Queue, error) {
+	q := &Queue{Mode: ModeStream, Prefix: "queue:", Group: "workers", r: r}
+	if len(mode) > 0 && mode[0] == ModeList {
+		q.Mode = ModeList
+	}
+	if err := r.RegisterScript("queue:promote", promoteScript, 5); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+//添加任务，返回任务Id
+//@param payload 任务数据，[]byte及string原样保存，其他类型使用JSON序列化
+//@param opts 选项（可选）
+func (q *Queue) Enqueue(name string, payload interface{}, opts ...*Options) (string, error) {
+	if name == "" {
+		return "", ErrEmptyQueueName
+	}
+	opt := &Options{}
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+	job := &Job{Id: opt.Id, Queue: name, Priority: opt.Priority, MaxRetries: opt.MaxRetries, CreatedAt: nowMs()}
+	if job.Id == "" {
+		job.Id = newJobId()
+	}
+	if job.MaxRetries == 0 {
+		job.MaxRetries = DefaultMaxRetries
+	}
+	switch v := payload.(type) {
+	case []byte:
+		job.Payload = v
+	case string:
+		job.Payload = []byte(v)
+	default:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return "", err
+		}
+		job.Payload = data
+	}
+	if opt.Delay > 0 {
+		return job.Id, q.schedule(job, time.Now().Add(opt.Delay))
+	}
+	return job.Id, q.push(job)
+}
+
+//待执行的任务数（不包括延迟任务及执行中的任务）
+func (q *Queue) Len(name string) (int64, error) {
+	cmd := "LLEN"
+	if q.Mode == ModeStream {
+		cmd = "XLEN"
+	}
+	var total int64
+	for _, key := range q.readyKeys(name) {
+		key = q.r.GenerateKey(key)
+		n, err := redis.Int64(q.r.Do(cmd, key))
+		if err != nil {
+			return 0, err
+		}
+		total += n
+		if q.Mode == ModeStream { //Stream中包括已读取未确认的任务，消费组不存在时忽略
+			if pending, err := redis.Values(q.r.Do("XPENDING", key, q.Group)); err == nil && len(pending) > 0 {
+				n, _ = redis.Int64(pending[0], nil)
+				total -= n
+			}
+		}
+	}
+	return total, nil
+}
+
+//延迟执行的任务数（包括等待重试的任务）
+func (q *Queue) DelayedLen(name string) (int64, error) {
+	return redis.Int64(q.r.Do("ZCARD", q.r.GenerateKey(q.key(name, "delayed"))))
+}
+
+//获取死信队列中的任务，最近失败的在前
+//@param start 开始位置
+//@param stop 结束位置，-1为最后一个
+func (q *Queue) DeadJobs(name string, start int64, stop int64) ([]*Job, error) {
+	raws, err := redis.Strings(q.r.Do("LRANGE", q.r.GenerateKey(q.key(name, "dead")), start, stop))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(raws))
+	for _, raw := range raws {
+		if job, err := parseJob(raw); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+//将死信队列中的任务重新加入队列，失败次数清零，返回重新加入的个数
+func (q *Queue) RetryDead(name string) (int, error) {
+	deadKey := q.r.GenerateKey(q.key(name, "dead"))
+	n := 0
+	for {
+		raw, err := redis.String(q.r.Do("RPOP", deadKey))
+		if err == redis.ErrNil {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		job, err := parseJob(raw)
+		if err != nil {
+			continue
+		}
+		job.Attempts, job.Error = 0, ""
+		if err = q.push(job); err != nil {
+			q.r.Do("RPUSH", deadKey, raw)
+			return n, err
+		}
+		n++
+	}
+}
+
+//清空死信队列
+func (q *Queue) ClearDead(name string) error {
+	_, err := q.r.Do("DEL", q.r.GenerateKey(q.key(name, "dead")))
+	return err
+}
+
+//将任务加入对应优先级的待执行队列
+func (q *Queue) push(job *Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	key := q.readyKey(job.Queue, job.Priority)
+	if q.Mode == ModeStream {
+		_, err = q.r.Do("XADD", q.r.GenerateKey(key), "*", "job", raw)
+		return err
+	}
+	return q.r.Tx(func(tx *Cache.Tx) error {
+		tx.Do("LPUSH", key, raw)
+		q.notify(tx.Pipe, job.Queue)
+		return nil
+	})
+}
+
+//通知阻塞等待的执行者有新任务（List模式），执行者收到通知后按优先级重新获取任务
+func (q *Queue) notify(p *Cache.Pipe, name string) {
+	key := q.key(name, "notify")
+	p.Do("LPUSH", key, 1)
+	p.Do("LTRIM", key, 0, NotifyMax-1)
+}
+
+//将任务加入延迟队列
+func (q *Queue) schedule(job *Job, at time.Time) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = q.r.Do("ZADD", q.r.GenerateKey(q.key(job.Queue, "delayed")), at.UnixNano()/int64(time.Millisecond), raw)
+	return err
+}
+
+//将到期的延迟任务转移到待执行队列，返回转移的个数
+func (q *Queue) promote(name string) (int, error) {
+	keys := append([]string{q.key(name, "delayed")}, q.readyKeys(name)...)
+	keys = append(keys, q.key(name, "notify"))
+	return redis.Int(q.r.RunScript("queue:promote", keys, nowMs(), PromoteBatch, q.Mode, NotifyMax))
+}
+
+//队列的Key（不含RedisModel的Key前缀）
+func (q *Queue) key(name string, suffix string) string {
+	return fmt.Sprintf("%s{%s}:%s", q.Prefix, name, suffix)
+}
+
+//优先级对应的待执行队列
+func (q *Queue) readyKey(name string, priority int) string {
+	switch {
+	case priority >= PriorityHigh:
+		return q.key(name, "high")
+	case priority <= PriorityLow:
+		return q.key(name, "low")
+	}
+	return q.key(name, "normal")
+}
+
+//所有待执行队列，按低、普通、高优先级排列
+func (q *Queue) readyKeys(name string) []string {
+	return []string{q.readyKey(name, PriorityLow), q.readyKey(name, PriorityNormal), q.readyKey(name, PriorityHigh)}
+}
+
+//将任务数据解析到v（JSON）
+func (job *Job) Bind(v interface{}) error {
+	return json.Unmarshal(job.Payload, v)
+}
+
+//解析任务
+func parseJob(raw string) (*Job, error) {
+	job := &Job{}
+	if err := json.Unmarshal([]byte(raw), job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+//生成任务Id：毫秒时间戳+随机数
+func newJobId() string {
+	return fmt.Sprintf("%x-%s", nowMs(), randomHex(8))
+}
+
+//n个字节的随机数（十六进制）
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,546 @@
+/*
+	任务队列的执行者，多个协程并发获取并执行任务，任务至少执行一次，处理函数需要支持重复执行
+	处理函数返回错误或panic时按指数退避延迟重试，超过最大重试次数后放入死信队列
+	任务执行期间定期延长可见性超时，进程退出等原因超时未确认的任务由其他执行者收回并按失败处理
+	Stop时不再获取新任务，等待执行中的任务完成
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/cache/redigo/redis"
+	"github.com/misgo/aresgo/text"
+)
+
+var (
+	DefaultConcurrency       = 10               //默认并发执行的协程数
+	DefaultVisibilityTimeout = 30 * time.Second //默认可见性超时，超过此时间未确认的任务被收回
+	RetryBackoff             = time.Second      //第一次重试的延迟时间，之后每次加倍
+	MaxRetryBackoff          = 10 * time.Minute //重试的最大延迟时间
+	PollInterval             = time.Second      //阻塞获取任务的超时时间及转移延迟任务的间隔
+
+	ErrWorkerStarted = errors.New("执行者已启动")
+	ErrStopTimeout   = errors.New("等待执行中的任务超时")
+
+	errVisibilityTimeout = errors.New("任务执行超时，未在可见性超时内确认")
+
+	//收回超时的任务（List模式）
+	//KEYS[1]执行中的任务，KEYS[2]超时时间，KEYS[3]延迟任务，KEYS[4]死信队列
+	//ARGV[1]原任务，ARGV[2]更新后的任务，ARGV[3]是否重试，ARGV[4]重试时间（毫秒），ARGV[5]死信队列最大任务数
+	reclaimScript = `
+if redis.call('ZREM', KEYS[2], ARGV[1]) == 0 then return 0 end
+redis.call('LREM', KEYS[1], 1, ARGV[1])
+if ARGV[3] == '1' then
+	redis.call('ZADD', KEYS[3], ARGV[4], ARGV[2])
+else
+	redis.call('LPUSH', KEYS[4], ARGV[2])
+	redis.call('LTRIM', KEYS[4], 0, tonumber(ARGV[5]) - 1)
+end
+return 1`
+)
+
+type (
+	//任务处理函数，返回错误时重试
+	Handler func(job *Job) error
+
+	//执行者，Start前可修改配置
+	Worker struct {
+		Concurrency       int                              //并发执行的协程数
+		VisibilityTimeout time.Duration                    //可见性超时
+		Backoff           func(attempts int) time.Duration //重试的延迟时间，为空时使用指数退避
+
+		q        *Queue
+		name     string
+		handler  Handler
+		consumer string //Stream模式的消费者名称
+
+		mu      sync.Mutex
+		started bool
+		stop    chan struct{}
+		wg      sync.WaitGroup
+	}
+
+	//获取到的任务
+	delivery struct {
+		job *Job
+		raw string
+		key string //Stream的Key（不含RedisModel的Key前缀）
+		id  string //Stream的消息Id
+	}
+)
+
+//创建执行者
+//@param name 队列名称
+//@param handler 任务处理函数
+func (q *Queue) NewWorker(name string, handler Handler) *Worker {
+	return &Worker{
+		Concurrency:       DefaultConcurrency,
+		VisibilityTimeout: DefaultVisibilityTimeout,
+		q:                 q,
+		name:              name,
+		handler:           handler,
+	}
+}
+
+//启动执行者，Stream模式创建消费组
+func (w *Worker) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return ErrWorkerStarted
+	}
+	if w.name == "" {
+		return ErrEmptyQueueName
+	}
+	if w.Concurrency < 1 {
+		w.Concurrency = DefaultConcurrency
+	}
+	if w.VisibilityTimeout <= 0 {
+		w.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if w.q.Mode == ModeStream {
+		for _, key := range w.q.readyKeys(w.name) {
+			_, err := w.q.r.Do("XGROUP", "CREATE", w.q.r.GenerateKey(key), w.q.Group, "0", "MKSTREAM")
+			if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				return err
+			}
+		}
+	} else if err := w.q.r.RegisterScript("queue:reclaim", reclaimScript, 4); err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	w.consumer = fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), randomHex(4))
+	w.stop = make(chan struct{})
+	w.started = true
+	w.wg.Add(w.Concurrency + 1)
+	for i := 0; i < w.Concurrency; i++ {
+		go w.loop()
+	}
+	go w.schedule()
+	return nil
+}
+
+//停止执行者，不再获取新任务并等待执行中的任务完成
+//@param timeout 等待的最长时间，小于等于0时一直等待；超时返回ErrStopTimeout，未完成的任务在可见性超时后由其他执行者收回
+func (w *Worker) Stop(timeout time.Duration) error {
+	w.mu.Lock()
+	if !w.started {
+		w.mu.Unlock()
+		return nil
+	}
+	w.started = false
+	close(w.stop)
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	if timeout > 0 {
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			return ErrStopTimeout
+		}
+	} else {
+		<-done
+	}
+	w.removeConsumer()
+	return nil
+}
+
+//获取并执行任务
+func (w *Worker) loop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		var deliveries []*delivery
+		var err error
+		if w.q.Mode == ModeStream {
+			deliveries, err = w.fetchStream()
+		} else {
+			deliveries, err = w.fetchList()
+		}
+		if err != nil {
+			Text.Log("error").Error(fmt.Sprintf("queue %s fetch error:%s", w.name, err.Error()))
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(PollInterval):
+			}
+			continue
+		}
+		for _, d := range deliveries {
+			w.process(d)
+		}
+	}
+}
+
+//定时转移到期的延迟任务并收回超时的任务
+func (w *Worker) schedule() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := w.q.promote(w.name)
+			if err != nil {
+				Text.Log("error").Error(fmt.Sprintf("queue %s promote error:%s", w.name, err.Error()))
+			}
+			if err != nil || n < PromoteBatch {
+				break
+			}
+		}
+		if w.q.Mode == ModeStream {
+			w.reclaimStream()
+		} else {
+			w.reclaimList()
+		}
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//延长可见性超时的间隔，为可见性超时的1/3，最小1毫秒
+func extendInterval(timeout time.Duration) time.Duration {
+	if interval := timeout / 3; interval > time.Millisecond {
+		return interval
+	}
+	return time.Millisecond
+}
+
+//执行任务，执行期间定期延长可见性超时
+func (w *Worker) process(d *delivery) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(extendInterval(w.VisibilityTimeout))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				w.extend(d)
+			}
+		}
+	}()
+	err := w.call(d.job)
+	close(done)
+	if err == nil {
+		w.ack(d)
+	} else {
+		w.fail(d, err)
+	}
+}
+
+//调用处理函数，panic时返回错误
+func (w *Worker) call(job *Job) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("处理任务时发生panic：%v", e)
+		}
+	}()
+	return w.handler(job)
+}
+
+//按优先级从高到低获取任务，都没有任务时阻塞等待所有优先级的任务
+func (w *Worker) fetchStream() ([]*delivery, error) {
+	keys := w.q.readyKeys(w.name)
+	for i := len(keys) - 1; i >= 0; i-- {
+		if deliveries, err := w.readGroup(keys[i:i+1], false); err != nil || len(deliveries) > 0 {
+			return deliveries, err
+		}
+	}
+	return w.readGroup([]string{keys[2], keys[1], keys[0]}, true)
+}
+
+//通过消费组读取新任务
+func (w *Worker) readGroup(keys []string, block bool) ([]*delivery, error) {
+	args := []interface{}{"GROUP", w.q.Group, w.consumer, "COUNT", 1}
+	if block {
+		args = append(args, "BLOCK", int64(PollInterval/time.Millisecond))
+	}
+	args = append(args, "STREAMS")
+	for _, key := range keys {
+		args = append(args, w.q.r.GenerateKey(key))
+	}
+	for range keys {
+		args = append(args, ">")
+	}
+	streams, err := redis.Values(w.q.r.Do("XREADGROUP", args...))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []*delivery
+	for _, stream := range streams {
+		pair, err := redis.Values(stream, nil)
+		if err != nil || len(pair) < 2 {
+			continue
+		}
+		key, _ := redis.String(pair[0], nil)
+		deliveries = append(deliveries, w.parseEntries(strings.TrimPrefix(key, w.q.r.KeyPre), pair[1])...)
+	}
+	return deliveries, nil
+}
+
+//解析Stream的消息，无法解析的消息直接确认并删除
+func (w *Worker) parseEntries(key string, reply interface{}) []*delivery {
+	entries, _ := redis.Values(reply, nil)
+	var deliveries []*delivery
+	for _, entry := range entries {
+		item, err := redis.Values(entry, nil)
+		if err != nil || len(item) < 1 {
+			continue
+		}
+		d := &delivery{key: key}
+		d.id, _ = redis.String(item[0], nil)
+		if len(item) > 1 {
+			fields, _ := redis.StringMap(item[1], nil)
+			d.raw = fields["job"]
+		}
+		if d.job, err = parseJob(d.raw); err != nil {
+			Text.Log("error").Error(fmt.Sprintf("queue %s drop invalid job %s:%s", w.name, d.id, d.raw))
+			w.ack(d)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+//按优先级从高到低获取任务并移到执行中的队列
+//都没有任务时阻塞等待新任务通知（任意优先级），收到通知或超时后重新按优先级获取，高优先级的任务不需要等待普通优先级的阻塞超时
+func (w *Worker) fetchList() ([]*delivery, error) {
+	keys := w.q.readyKeys(w.name)
+	processing := w.q.r.GenerateKey(w.q.key(w.name, "processing"))
+	var raw string
+	var err error
+	for i := len(keys) - 1; i >= 0; i-- {
+		if raw, err = redis.String(w.q.r.Do("RPOPLPUSH", w.q.r.GenerateKey(keys[i]), processing)); err != redis.ErrNil {
+			break
+		}
+	}
+	if err == redis.ErrNil {
+		timeout := int64(PollInterval / time.Second)
+		if timeout < 1 {
+			timeout = 1
+		}
+		_, err = w.q.r.Do("BRPOP", w.q.r.GenerateKey(w.q.key(w.name, "notify")), timeout)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	d := &delivery{raw: raw}
+	if _, err = w.q.r.Do("ZADD", w.q.r.GenerateKey(w.q.key(w.name, "deadline")), w.deadline(), raw); err != nil {
+		Text.Log("error").Error(fmt.Sprintf("queue %s deadline error:%s", w.name, err.Error()))
+	}
+	if d.job, err = parseJob(raw); err != nil {
+		Text.Log("error").Error(fmt.Sprintf("queue %s drop invalid job:%s", w.name, raw))
+		w.ack(d)
+		return nil, nil
+	}
+	return []*delivery{d}, nil
+}
+
+//延长可见性超时，任务已被收回时不再延长
+func (w *Worker) extend(d *delivery) {
+	var err error
+	if w.q.Mode == ModeStream { //重新认领消息以重置空闲时间
+		_, err = w.q.r.Do("XCLAIM", w.q.r.GenerateKey(d.key), w.q.Group, w.consumer, 0, d.id, "JUSTID")
+	} else {
+		_, err = w.q.r.Do("ZADD", w.q.r.GenerateKey(w.q.key(w.name, "deadline")), "XX", w.deadline(), d.raw)
+	}
+	if err != nil {
+		Text.Log("error").Error(fmt.Sprintf("queue %s extend error:%s", w.name, err.Error()))
+	}
+}
+
+//确认任务执行完成
+func (w *Worker) ack(d *delivery) {
+	err := w.q.r.Tx(func(tx *Cache.Tx) error {
+		w.remove(tx, d)
+		return nil
+	})
+	if err != nil {
+		Text.Log("error").Error(fmt.Sprintf("queue %s ack error:%s", w.name, err.Error()))
+	}
+}
+
+//任务执行失败，未超过最大重试次数时延迟重试，否则放入死信队列
+func (w *Worker) fail(d *delivery, reason error) {
+	next, raw, retry, at := w.retry(d.job, reason)
+	err := w.q.r.Tx(func(tx *Cache.Tx) error {
+		w.remove(tx, d)
+		w.requeue(tx.Pipe, raw, retry, at)
+		return nil
+	})
+	if err != nil {
+		Text.Log("error").Error(fmt.Sprintf("queue %s fail error:%s", w.name, err.Error()))
+		return
+	}
+	w.logFailure(next, retry)
+}
+
+//从执行中的任务删除
+func (w *Worker) remove(tx *Cache.Tx, d *delivery) {
+	if w.q.Mode == ModeStream {
+		tx.Do("XACK", d.key, w.q.Group, d.id)
+		tx.Do("XDEL", d.key, d.id)
+	} else {
+		tx.Do("LREM", w.q.key(w.name, "processing"), 1, d.raw)
+		tx.Do("ZREM", w.q.key(w.name, "deadline"), d.raw)
+	}
+}
+
+//加入延迟队列重试或放入死信队列
+func (w *Worker) requeue(p *Cache.Pipe, raw []byte, retry bool, at int64) {
+	if retry {
+		p.Do("ZADD", w.q.key(w.name, "delayed"), at, raw)
+		return
+	}
+	dead := w.q.key(w.name, "dead")
+	p.Do("LPUSH", dead, raw)
+	p.Do("LTRIM", dead, 0, DeadLetterMax-1)
+}
+
+//更新失败次数，返回更新后的任务、是否重试及重试时间（毫秒）
+func (w *Worker) retry(job *Job, reason error) (*Job, []byte, bool, int64) {
+	next := *job
+	next.Attempts++
+	next.Error = reason.Error()
+	retry := next.MaxRetries >= 0 && next.Attempts <= next.MaxRetries
+	raw, _ := json.Marshal(&next)
+	return &next, raw, retry, nowMs() + int64(w.backoff(next.Attempts)/time.Millisecond)
+}
+
+//记录任务失败的日志
+func (w *Worker) logFailure(job *Job, retry bool) {
+	if retry {
+		Text.Log("error").Error(fmt.Sprintf("queue %s job %s failed(%d/%d):%s", w.name, job.Id, job.Attempts, job.MaxRetries, job.Error))
+	} else {
+		Text.Log("error").Error(fmt.Sprintf("queue %s job %s dead:%s", w.name, job.Id, job.Error))
+	}
+}
+
+//重试的延迟时间
+func (w *Worker) backoff(attempts int) time.Duration {
+	if w.Backoff != nil {
+		return w.Backoff(attempts)
+	}
+	if attempts > 30 {
+		return MaxRetryBackoff
+	}
+	d := RetryBackoff << uint(attempts-1)
+	if d <= 0 || d > MaxRetryBackoff {
+		return MaxRetryBackoff
+	}
+	return d
+}
+
+//收回超时的任务（Stream模式），认领空闲时间超过可见性超时的消息后按失败处理
+func (w *Worker) reclaimStream() {
+	timeout := int64(w.VisibilityTimeout / time.Millisecond)
+	for _, key := range w.q.readyKeys(w.name) {
+		fullKey := w.q.r.GenerateKey(key)
+		pending, err := redis.Values(w.q.r.Do("XPENDING", fullKey, w.q.Group, "-", "+", 100))
+		if err != nil {
+			continue
+		}
+		for _, item := range pending {
+			info, err := redis.Values(item, nil)
+			if err != nil || len(info) < 3 {
+				continue
+			}
+			if idle, _ := redis.Int64(info[2], nil); idle < timeout {
+				continue
+			}
+			id, _ := redis.String(info[0], nil)
+			claimed, err := w.q.r.Do("XCLAIM", fullKey, w.q.Group, w.consumer, timeout, id)
+			if err != nil {
+				continue
+			}
+			for _, d := range w.parseEntries(key, claimed) {
+				w.fail(d, errVisibilityTimeout)
+			}
+		}
+	}
+}
+
+//收回超时的任务（List模式）
+func (w *Worker) reclaimList() {
+	processingKey := w.q.key(w.name, "processing")
+	deadlineKey := w.q.key(w.name, "deadline")
+	//获取任务后未设置超时时间（如进程退出）的任务补充超时时间
+	if raws, err := redis.Strings(w.q.r.Do("LRANGE", w.q.r.GenerateKey(processingKey), -100, -1)); err == nil && len(raws) > 0 {
+		w.q.r.Pipeline(func(p *Cache.Pipe) {
+			for _, raw := range raws {
+				p.Do("ZADD", deadlineKey, "NX", w.deadline(), raw)
+			}
+		})
+	}
+	expired, err := redis.Strings(w.q.r.Do("ZRANGEBYSCORE", w.q.r.GenerateKey(deadlineKey), "-inf", nowMs(), "LIMIT", 0, 100))
+	if err != nil {
+		return
+	}
+	keys := []string{processingKey, deadlineKey, w.q.key(w.name, "delayed"), w.q.key(w.name, "dead")}
+	for _, raw := range expired {
+		job, err := parseJob(raw)
+		if err != nil {
+			w.ack(&delivery{raw: raw})
+			continue
+		}
+		next, data, retry, at := w.retry(job, errVisibilityTimeout)
+		flag := 0
+		if retry {
+			flag = 1
+		}
+		ok, err := redis.Int(w.q.r.RunScript("queue:reclaim", keys, raw, data, flag, at, DeadLetterMax))
+		if err != nil {
+			Text.Log("error").Error(fmt.Sprintf("queue %s reclaim error:%s", w.name, err.Error()))
+		} else if ok == 1 {
+			w.logFailure(next, retry)
+		}
+	}
+}
+
+//删除没有待确认消息的消费者（Stream模式）
+func (w *Worker) removeConsumer() {
+	if w.q.Mode != ModeStream {
+		return
+	}
+	for _, key := range w.q.readyKeys(w.name) {
+		fullKey := w.q.r.GenerateKey(key)
+		pending, err := redis.Values(w.q.r.Do("XPENDING", fullKey, w.q.Group, "-", "+", 1, w.consumer))
+		if err == nil && len(pending) == 0 {
+			w.q.r.Do("XGROUP", "DELCONSUMER", fullKey, w.q.Group, w.consumer)
+		}
+	}
+}
+
+//可见性超时的截止时间（毫秒）
+func (w *Worker) deadline() int64 {
+	return nowMs() + int64(w.VisibilityTimeout/time.Millisecond)
+}
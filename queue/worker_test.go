@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+//默认按指数退避，不超过MaxRetryBackoff；设置Backoff时使用自定义的延迟时间
+func TestWorkerBackoff(t *testing.T) {
+	w := &Worker{}
+	cases := map[int]time.Duration{
+		1:  RetryBackoff,
+		2:  2 * RetryBackoff,
+		4:  8 * RetryBackoff,
+		20: MaxRetryBackoff,
+		31: MaxRetryBackoff,
+		64: MaxRetryBackoff,
+	}
+	for attempts, want := range cases {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("第%d次重试的延迟应为%v，实际为%v", attempts, want, got)
+		}
+	}
+	w.Backoff = func(attempts int) time.Duration { return time.Duration(attempts) * time.Millisecond }
+	if got := w.backoff(3); got != 3*time.Millisecond {
+		t.Errorf("应使用自定义的延迟时间，实际为%v", got)
+	}
+}
+
+//失败次数未超过最大重试次数时重试，MaxRetries小于0时不重试
+func TestWorkerRetry(t *testing.T) {
+	w := &Worker{Backoff: func(int) time.Duration { return time.Second }}
+	cases := []struct {
+		attempts   int
+		maxRetries int
+		retry      bool
+	}{
+		{0, 3, true},
+		{2, 3, true},
+		{3, 3, false},
+		{0, 0, false},
+		{0, -1, false},
+	}
+	reason := errors.New("timeout")
+	for _, c := range cases {
+		job := &Job{Id: "1", Attempts: c.attempts, MaxRetries: c.maxRetries}
+		before := nowMs()
+		next, raw, retry, at := w.retry(job, reason)
+		if retry != c.retry {
+			t.Errorf("%d/%d: 是否重试应为%v", c.attempts, c.maxRetries, c.retry)
+		}
+		if job.Attempts != c.attempts {
+			t.Errorf("%d/%d: 不应修改原任务", c.attempts, c.maxRetries)
+		}
+		if next.Attempts != c.attempts+1 || next.Error != reason.Error() {
+			t.Errorf("%d/%d: 失败次数或错误信息错误：%+v", c.attempts, c.maxRetries, next)
+		}
+		var decoded Job
+		if err := json.Unmarshal(raw, &decoded); err != nil || decoded.Attempts != next.Attempts {
+			t.Errorf("%d/%d: 更新后的任务数据错误：%s", c.attempts, c.maxRetries, raw)
+		}
+		if at < before+1000 || at > nowMs()+1000 {
+			t.Errorf("%d/%d: 重试时间错误：%d", c.attempts, c.maxRetries, at)
+		}
+	}
+}
+
+//优先级对应的待执行队列，超出范围的优先级归入最高或最低
+func TestReadyKey(t *testing.T) {
+	q := &Queue{Prefix: "queue:"}
+	cases := map[int]string{
+		-5:             "queue:{sms}:low",
+		PriorityLow:    "queue:{sms}:low",
+		PriorityNormal: "queue:{sms}:normal",
+		PriorityHigh:   "queue:{sms}:high",
+		9:              "queue:{sms}:high",
+	}
+	for priority, want := range cases {
+		if got := q.readyKey("sms", priority); got != want {
+			t.Errorf("优先级%d的队列应为%s，实际为%s", priority, want, got)
+		}
+	}
+	if keys := q.readyKeys("sms"); len(keys) != 3 || keys[0] != cases[PriorityLow] || keys[2] != cases[PriorityHigh] {
+		t.Errorf("待执行队列的顺序错误：%v", keys)
+	}
+}
+
+//延长可见性超时的间隔为可见性超时的1/3，最小1毫秒
+func TestExtendInterval(t *testing.T) {
+	cases := map[time.Duration]time.Duration{
+		30 * time.Second:     10 * time.Second,
+		3 * time.Millisecond: time.Millisecond,
+		2 * time.Nanosecond:  time.Millisecond,
+		time.Nanosecond:      time.Millisecond,
+	}
+	for timeout, want := range cases {
+		if got := extendInterval(timeout); got != want {
+			t.Errorf("%v: 间隔应为%v，实际为%v", timeout, want, got)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+	限流拦截器，按客户端IP或用户ID限流，设置X-RateLimit-*响应头，超出限制时返回429及Retry-After响应头
+	使用方法：
+	limiter := R("default").NewRateLimiter(Cache.RateLimitTokenBucket, 100, time.Minute)
+	router.Get("/api/list", list, aresgo.RateLimit(limiter))
+	router.Post("/api/order", order, aresgo.RateLimit(limiter, func(ctx *aresgo.Context) string {
+		return string(ctx.GetCookie("uid"))
+	}))
+	@author : hyperion
+	@since  : 2026-10-19
+	@version: 1.0
+*/
+package aresgo
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/router/fasthttp"
+	"github.com/misgo/aresgo/text"
+)
+
+//限流拦截器，限流器出错时放行请求并记录日志；超出限制时已写入429响应，Router.HttpModuleIntercept仍会被调用
+//@param limiter 限流器
+//@param keyFunc 获取限流对象的函数（可选），默认使用客户端IP，返回空字符串时不限流
+func RateLimit(limiter *Cache.RateLimiter, keyFunc ...func(ctx *Context) string) HttpModule {
+	getKey := RateLimitByIP
+	if len(keyFunc) > 0 && keyFunc[0] != nil {
+		getKey = keyFunc[0]
+	}
+	return func(ctx *Context) bool {
+		key := getKey(ctx)
+		if key == "" {
+			return true
+		}
+		res, err := limiter.Allow(key)
+		if err != nil {
+			Text.Log("error").Error(fmt.Sprintf("rate limit error:%s", err.Error()))
+			return true
+		}
+		return writeRateLimit(ctx, res)
+	}
+}
+
+//设置限流响应头，超出限制时写入429响应并返回false
+func writeRateLimit(ctx *Context, res *Cache.RateLimitResult) bool {
+	ctx.Response.Header.Set("X-RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+	ctx.Response.Header.Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+	ctx.Response.Header.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+	if res.Allowed {
+		return true
+	}
+	ctx.Response.Header.Set("Retry-After", strconv.FormatInt(ceilSeconds(res.RetryAfter), 10))
+	ctx.SetStatusCode(fasthttp.StatusTooManyRequests)
+	ctx.SetContentTypeBytes(defaultContentType)
+	ctx.SetBodyString(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests))
+	return false
+}
+
+//按客户端IP限流
+func RateLimitByIP(ctx *Context) string {
+	return "ip:" + ctx.RemoteIP().String()
+}
+
+//时间向上取整为秒，负数按0处理
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
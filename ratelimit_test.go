@@ -0,0 +1,62 @@
+package aresgo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/misgo/aresgo/cache"
+	"github.com/misgo/aresgo/router/fasthttp"
+)
+
+//允许时只设置X-RateLimit-*响应头，超出限制时返回429及Retry-After
+func TestWriteRateLimit(t *testing.T) {
+	cases := []struct {
+		name    string
+		res     Cache.RateLimitResult
+		allowed bool
+		headers map[string]string
+	}{
+		{"allowed", Cache.RateLimitResult{Allowed: true, Limit: 100, Remaining: 99, Reset: 59500 * time.Millisecond}, true,
+			map[string]string{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "99", "X-RateLimit-Reset": "60", "Retry-After": ""}},
+		{"rejected", Cache.RateLimitResult{Limit: 100, RetryAfter: 1001 * time.Millisecond, Reset: time.Minute}, false,
+			map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "60", "Retry-After": "2"}},
+		{"negative", Cache.RateLimitResult{Limit: 100, RetryAfter: -2 * time.Millisecond, Reset: -2 * time.Millisecond}, false,
+			map[string]string{"X-RateLimit-Reset": "0", "Retry-After": "0"}},
+	}
+	for _, c := range cases {
+		ctx := &Context{RequestCtx: &fasthttp.RequestCtx{}}
+		res := c.res
+		if got := writeRateLimit(ctx, &res); got != c.allowed {
+			t.Errorf("%s: 是否放行应为%v", c.name, c.allowed)
+		}
+		for k, want := range c.headers {
+			if got := string(ctx.Response.Header.Peek(k)); got != want {
+				t.Errorf("%s: 响应头%s应为%q，实际为%q", c.name, k, want, got)
+			}
+		}
+		status := fasthttp.StatusOK
+		if !c.allowed {
+			status = fasthttp.StatusTooManyRequests
+		}
+		if ctx.Response.StatusCode() != status {
+			t.Errorf("%s: 状态码应为%d，实际为%d", c.name, status, ctx.Response.StatusCode())
+		}
+	}
+}
+
+//时间向上取整为秒，负数按0处理
+func TestCeilSeconds(t *testing.T) {
+	cases := map[time.Duration]int64{
+		0:                0,
+		-time.Second:     0,
+		time.Millisecond: 1,
+		time.Second:      1,
+		time.Second + 1:  2,
+		90 * time.Second: 90,
+	}
+	for d, want := range cases {
+		if got := ceilSeconds(d); got != want {
+			t.Errorf("%v: 应为%d秒，实际为%d", d, want, got)
+		}
+	}
+}